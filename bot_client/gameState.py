@@ -1,10 +1,13 @@
 # Enum class (for game mode)
 from enum import IntEnum
 
+# Type hints (for the rules of the game)
+from typing import Any
+
 # Struct class (for processing)
 from struct import unpack_from, pack
 
-# Internal representation of walls
+# Internal representation of walls (of the default maze)
 from walls import wallArr
 
 # Buffer to collect messages to write to the server
@@ -47,6 +50,35 @@ class GhostColors(IntEnum):
 SCATTER_ROW = [-3, -3, 31, 31]
 SCATTER_COL = [25,  2, 27,  0]
 
+# Space above the ghost house exit (where eyes head for, to enter the ghost house),
+# in the default maze - red's spawn, unless the server reports another maze
+GHOST_HOUSE_ROW = 11
+GHOST_HOUSE_COL = 13
+
+# Game rules (used in simulation), unless the server reports others
+DEFAULT_RULES: dict[str, Any] = {
+	'PelletPoints':      10,
+	'SuperPelletPoints': 50,
+	'AngerThreshold1':   20,
+	'UpOverflowBug':     False,
+	'CruiseElroy':       False,
+	'ElroySpeedup':      1,
+	'GhostEyes':         False,
+	'EyesSpeed':         200,
+	'Levels': [{
+		'GhostFrightSteps': 40,
+		'Waves':            [60, 180],
+		'RepeatWaves':      True,
+		'FruitType':        0
+	}],
+	'Fruits': [{
+		'Name':       'cherry',
+		'Points':     100,
+		'Duration':   30,
+		'Thresholds': [174, 74]
+	}]
+}
+
 class Directions(IntEnum):
 	'''
 	Enum of possible directions for the Pacman agent
@@ -209,23 +241,42 @@ class Ghost:
 		self.spawning: bool = bool(True)
 		self.eaten: bool = bool(True)
 
+		# Whether the ghost is returning to the ghost house as eyes (with 'GhostEyes')
+		self.eyes: bool = bool(False)
+
+		# Pellet counter, and whether the ghost waits for it (with 'PelletRelease')
+		self.pelletCounter: int = 0
+		self.held: bool = bool(False)
+
+		# Ticks between the ghost's moves (it moves when the ticks are a multiple)
+		self.movePeriod: int = 12
+
 		# (For simulation) Planned next direction the ghost will take
 		self.plannedDirection: Directions = Directions.NONE
 
+	def ticksUntilMove(self) -> int:
+		'''
+		Helper function to get the number of ticks until the ghost next moves
+		(0 if it moves on the current tick)
+		'''
+
+		return -self.state.currTicks % max(1, self.movePeriod)
+
 	def updateAux(self, auxInfo: int) -> None:
 		'''
 		Update auxiliary info (fright steps and spawning flag, 1 byte)
 		'''
 
-		self.frightSteps = auxInfo & 0x3f
+		self.frightSteps = auxInfo & 0x7f
 		self.spawning = bool(auxInfo >> 7)
 
 	def updateAux2(self, auxInfo: int) -> None:
 		'''
-		Update second auxiliary info (trapped steps and eaten flag, 1 byte)
+		Update second auxiliary info (trapped steps, eyes and eaten flags, 1 byte)
 		'''
 
 		self.trappedSteps = auxInfo & 0x3f
+		self.eyes = bool((auxInfo >> 6) & 1)
 		self.eaten = bool(auxInfo >> 7)
 
 	def serializeAux(self) -> int:
@@ -237,10 +288,10 @@ class Ghost:
 
 	def serializeAux2(self) -> int:
 		'''
-		Serialize second auxiliary info (trapped steps and eaten flag, 1 byte)
+		Serialize second auxiliary info (trapped steps, eyes and eaten flags, 1 byte)
 		'''
 
-		return (self.eaten << 7) | (self.trappedSteps)
+		return (self.eaten << 7) | (self.eyes << 6) | (self.trappedSteps)
 
 	def isFrightened(self) -> bool:
 		'''
@@ -272,6 +323,13 @@ class Ghost:
 		if self.isFrightened():
 			self.frightSteps -= 1
 
+		# Once eyes reach the ghost house, they enter it to come back to life
+		# (so treat the ghost as spawning, as above)
+		if self.eyes and self.location.at(self.state.ghostHouseRow, self.state.ghostHouseCol):
+			self.eyes = False
+			self.eaten = False
+			self.spawning = True
+
 	def guessPlan(self) -> None:
 		'''
 		Use incomplete knowledge of the current game state to predict where the
@@ -305,8 +363,19 @@ class Ghost:
 		targetRow: int = 0
 		targetCol: int = 0
 
+		# With the arcade game's overflow bug, targets ahead of Pacman are also
+		# shifted left when Pacman faces up (only affects pink and cyan)
+		overflow: int = 0
+		if self.state.rules['UpOverflowBug'] and pacmanRowDir == -1 and pacmanColDir == 0:
+			overflow = 1
+
+		# With Cruise Elroy, red keeps chasing in scatter mode
+		gameMode: GameModes = self.state.gameMode
+		if self.color == GhostColors.RED and self.state.elroyStage > 0:
+			gameMode = GameModes.CHASE
+
 		# Choose a target for the ghost based on its color
-		if self.state.gameMode == GameModes.CHASE:
+		if gameMode == GameModes.CHASE:
 
 			# Red targets Pacman
 			if self.color == GhostColors.RED:
@@ -316,13 +385,13 @@ class Ghost:
 			# Pink targets the space 4 ahead of Pacman
 			elif self.color == GhostColors.PINK:
 				targetRow = pacmanRow + 4 * pacmanRowDir
-				targetRow = pacmanCol + 4 * pacmanColDir
+				targetCol = pacmanCol + 4 * (pacmanColDir - overflow)
 
 			# Cyan targets the position of red, reflected about the position 2 spaces
 			# ahead of Pacman
 			elif self.color == GhostColors.CYAN:
 				targetRow = 2 * pacmanRow + 4 * pacmanRowDir - redRow
-				targetCol = 2 * pacmanCol + 4 * pacmanColDir - redCol
+				targetCol = 2 * pacmanCol + 4 * (pacmanColDir - overflow) - redCol
 
 			# Orange targets Pacman, but only if Pacman is farther than 8 spaces away
 			elif self.color == GhostColors.ORANGE:
@@ -334,10 +403,15 @@ class Ghost:
 											SCATTER_COL[GhostColors.ORANGE]
 
 		# In scatter mode, each ghost tracks a fixed target at a corner of the maze
-		if self.state.gameMode == GameModes.SCATTER:
+		if gameMode == GameModes.SCATTER:
 			targetRow = SCATTER_ROW[self.color]
 			targetCol = SCATTER_COL[self.color]
 
+		# Eyes head back to the ghost house instead, whatever the mode
+		if self.eyes:
+			targetRow = self.state.ghostHouseRow
+			targetCol = self.state.ghostHouseCol
+
 		# Calculate the distance squared to the target, for all 4 moves
 		minDist = 0xfffffff
 		maxDist = -1
@@ -395,14 +469,18 @@ class GameState:
 	from the server to make querying the game state simple.
 	'''
 
-	def __init__(self) -> None:
+	def __init__(self, legacyProtocol: bool = False) -> None:
 		'''
-		Construct a new game state object
+		Construct a new game state object (legacy servers send 16-bit ticks
+		and score, rather than 32-bit)
 		'''
 
 		# Big endian format specifier
 		self.format: str = '>'
 
+		# Whether the server uses the legacy protocol
+		self.legacyProtocol: bool = legacyProtocol
+
 		# Internal variable to lock the state
 		self._locked: bool = False
 
@@ -412,15 +490,22 @@ class GameState:
 		# Buffer of messages to write back to the server
 		self.writeServerBuf: deque[ServerMessage] = deque[ServerMessage](maxlen=64)
 
-		# Internal representation of walls:
+		# Rules of the game (reported by the server when connecting)
+		self.rules: dict[str, Any] = dict(DEFAULT_RULES)
+
+		# Internal representation of walls (reported by the server when connecting):
 		# 31 * 4 bytes = 31 * (32-bit integer bitset)
 		self.wallArr: list[int] = wallArr
 
+		# Space above the ghost house exit (reported by the server when connecting)
+		self.ghostHouseRow: int = GHOST_HOUSE_ROW
+		self.ghostHouseCol: int = GHOST_HOUSE_COL
+
 		#--- Important game state attributes (from game engine) ---#
 
-		# 2 bytes
+		# 4 bytes (2 bytes if legacy)
 		self.currTicks: int = 0
-		self.format += 'H'
+		self.format += 'H' if legacyProtocol else 'I'
 
 		# 1 byte
 		self.updatePeriod: int = 12
@@ -439,9 +524,9 @@ class GameState:
 		self.levelSteps: int = 960
 		self.format += 'H'
 
-		# 2 bytes
+		# 4 bytes (2 bytes if legacy)
 		self.currScore: int = 0
-		self.format += 'H'
+		self.format += 'H' if legacyProtocol else 'I'
 
 		# 1 byte
 		self.currLevel: int = 0
@@ -472,10 +557,41 @@ class GameState:
 		self.fruitDuration: int = 30
 		self.format += 'BB'
 
+		# 1 byte (index into the 'Fruits' rule, not sent if legacy)
+		self.fruitType: int = 0
+		self.format += '' if legacyProtocol else 'B'
+
 		# 31 * 4 bytes = 31 * (32-bit integer bitset)
 		self.pelletArr: list[int] = [0 for _ in range(31)]
 		self.format += (31 * 'I')
 
+		# 8 bytes
+		self.seed: int = 0
+		self.format += 'q'
+
+		# 1 byte (index into the level's 'Waves' rule, to predict mode changes)
+		self.waveIdx: int = 0
+		self.format += 'B'
+
+		# 31 * 4 bytes = 31 * (32-bit integer bitset, of super pellet locations)
+		self.superPelletArr: list[int] = [0 for _ in range(31)]
+		self.format += (31 * 'I')
+
+		# 7 bytes = 4 ghost pellet counters + global counter + release timer + flags
+		self.globalPelletCounter: int = 0
+		self.globalCounterActive: bool = False
+		self.releaseSteps: int = 0
+		self.format += 'BBBBBBB'
+
+		# 1 byte (stage of red's Cruise Elroy speed-up, or 0 for none)
+		self.elroyStage: int = 0
+		self.format += 'B'
+
+		# 6 bytes = 4 ghost move periods + Pacman's move period + ticks until it can move
+		self.pacmanMovePeriod: int = 12
+		self.pacmanMoveWait: int = 0
+		self.format += 'BBBBBB'
+
 	def lock(self) -> None:
 		'''
 		Lock the game state, to prevent updates
@@ -566,9 +682,31 @@ class GameState:
 			self.fruitLoc.serialize(),
 			self.fruitSteps,
 			self.fruitDuration,
+			*([] if self.legacyProtocol else [self.fruitType]),
 
 			# Pellet info
-			*self.pelletArr
+			*self.pelletArr,
+
+			# Seed and wave info
+			self.seed,
+			self.waveIdx,
+
+			# Super pellet info
+			*self.superPelletArr,
+
+			# Ghost release info
+			*[ghost.pelletCounter for ghost in self.ghosts],
+			self.globalPelletCounter,
+			self.releaseSteps,
+			self.serializeReleaseFlags(),
+
+			# Cruise Elroy info
+			self.elroyStage,
+
+			# Speed info
+			*[ghost.movePeriod for ghost in self.ghosts],
+			self.pacmanMovePeriod,
+			self.pacmanMoveWait
 		)
 
 	def getGhostPlans(self) -> dict[GhostColors, Directions]:
@@ -630,13 +768,124 @@ class GameState:
 		self.fruitSteps = unpacked[24]
 		self.fruitDuration = unpacked[25]
 
+		# Fruit type info (only if not legacy, which shifts the rest)
+		idx: int = 26
+		if not self.legacyProtocol:
+			self.fruitType = unpacked[idx]
+			idx += 1
+
 		# Pellet info
-		self.pelletArr = list[int](unpacked)[26:]
+		self.pelletArr = list[int](unpacked)[idx:idx+31]
+
+		# Seed and wave info
+		self.seed    = unpacked[idx+31]
+		self.waveIdx = unpacked[idx+32]
+
+		# Super pellet info
+		self.superPelletArr = list[int](unpacked)[idx+33:idx+64]
+
+		# Ghost release info
+		for ghost in self.ghosts:
+			ghost.pelletCounter = unpacked[idx+64+ghost.color]
+		self.globalPelletCounter = unpacked[idx+68]
+		self.releaseSteps        = unpacked[idx+69]
+		self.updateReleaseFlags(unpacked[idx+70])
+
+		# Cruise Elroy info
+		self.elroyStage = unpacked[idx+71]
+
+		# Speed info
+		for ghost in self.ghosts:
+			ghost.movePeriod = unpacked[idx+72+ghost.color]
+		self.pacmanMovePeriod = unpacked[idx+76]
+		self.pacmanMoveWait   = unpacked[idx+77]
 
 		# Reset our guesses of the planned ghost directions
 		for ghost in self.ghosts:
 			ghost.plannedDirection = Directions.NONE
 
+	def updateReleaseFlags(self, flags: int) -> None:
+		'''
+		Update the ghost release flags (held ghosts and global counter, 1 byte)
+		'''
+
+		for ghost in self.ghosts:
+			ghost.held = bool((flags >> ghost.color) & 1)
+		self.globalCounterActive = bool(flags >> 7)
+
+	def serializeReleaseFlags(self) -> int:
+		'''
+		Serialize the ghost release flags (held ghosts and global counter, 1 byte)
+		'''
+
+		flags: int = self.globalCounterActive << 7
+		for ghost in self.ghosts:
+			flags |= ghost.held << ghost.color
+		return flags
+
+	def eyesMovePeriod(self) -> int:
+		'''
+		Helper function to get the number of ticks between the moves of eyes
+		(the update period, sped up by the rules' eyes speed)
+		'''
+
+		speed: int = self.rules['EyesSpeed']
+		return max(1, (self.updatePeriod * 100 + speed // 2) // speed)
+
+	def updateRules(self, rules: dict[str, Any]) -> None:
+		'''
+		Update the rules of the game, given the rules reported by the server
+		'''
+
+		self.rules.update(rules)
+
+	def updateMaze(self, maze: dict[str, Any]) -> None:
+		'''
+		Update the maze of the game, given the maze reported by the server
+		'''
+
+		self.wallArr = list(maze['Walls'])
+
+		# Eyes head for red's spawn, just outside the ghost house exit
+		self.ghostHouseRow, self.ghostHouseCol = maze['GhostSpawns'][GhostColors.RED]
+
+	def levelRules(self) -> dict[str, Any]:
+		'''
+		Helper function to get the rules for the current level (levels past
+		the end of the table use its last entry)
+		'''
+
+		levels: list[dict[str, Any]] = self.rules['Levels']
+		return levels[min(max(self.currLevel, 1), len(levels)) - 1]
+
+	def fruitRules(self) -> dict[str, Any]:
+		'''
+		Helper function to get the rules for the current level's fruit
+		'''
+
+		return self.rules['Fruits'][self.levelRules()['FruitType']]
+
+	def nextWave(self) -> None:
+		'''
+		Helper function to move on to the next wave of the mode schedule
+		(waves alternate between scatter and chase, starting with scatter),
+		for simulation purposes
+		'''
+
+		waves: list[int] = self.levelRules()['Waves']
+
+		# The chase wave after the last wave lasts forever (unless repeating)
+		if self.waveIdx >= len(waves):
+			return
+		self.waveIdx += 1
+		if self.levelRules()['RepeatWaves'] and self.waveIdx >= len(waves):
+			self.waveIdx = 0
+
+		# Update the mode and its steps to match the wave
+		self.gameMode = GameModes.CHASE if self.waveIdx % 2 else GameModes.SCATTER
+		self.modeSteps = waves[self.waveIdx] if self.waveIdx < len(waves) else 255
+		self.modeDuration = self.modeSteps
+
 	def updateGhostPlans(self, ghostPlans: dict[GhostColors, Directions]):
 		'''
 		Update this game state, given a list of ghost planned directions
@@ -658,7 +907,7 @@ class GameState:
 		'''
 
 		return self.pelletAt(row, col) and \
-			((row == 3) or (row == 23)) and ((col == 1) or (col == 26))
+			bool((self.superPelletArr[row] >> col) & 1)
 
 	def fruitAt(self, row: int, col: int) -> bool:
 		'''
@@ -682,7 +931,7 @@ class GameState:
 
 		# Remove the fruit if we have collected it
 		if self.fruitAt(row, col):
-			self.currScore += 100
+			self.currScore += self.fruitRules()['Points']
 			self.fruitSteps = 0
 			self.fruitLoc.row = 32
 			self.fruitLoc.col = 32
@@ -712,25 +961,27 @@ class GameState:
 		self.pelletArr[row] &= (~(1 << col))
 
 		# Increase the score by this amount
-		self.currScore += (50 if superPellet else 10)
+		self.currScore += self.rules['SuperPelletPoints' if superPellet else 'PelletPoints']
 
 		# Spawn the fruit based on the number of pellets, if applicable
+		# (at the default maze's fruit spawn, unless the fruit has its own)
 		numPellets = self.numPellets()
-		if numPellets == 174 or numPellets == 74:
-			self.fruitSteps = 30
-			self.fruitLoc.row = 17
-			self.fruitLoc.col = 13
+		fruit = self.fruitRules()
+		if numPellets in fruit['Thresholds'] and self.fruitSteps == 0:
+			self.fruitSteps = fruit['Duration']
+			self.fruitLoc.row, self.fruitLoc.col = fruit.get('Spawn', (17, 13))
 
-		# When <= 20 pellets are left, keep the game in chase mode
-		if numPellets <= 20:
+		# When the ghosts are angry, keep the game in chase mode
+		if numPellets <= self.rules['AngerThreshold1']:
 			if self.gameMode == GameModes.SCATTER:
 				self.gameMode = GameModes.CHASE
 
 		# Scare the ghosts, if applicable
 		if superPellet:
 			for ghost in self.ghosts:
-				ghost.frightSteps = 40
-				ghost.plannedDirection = reversedDirections[ghost.plannedDirection]
+				if not ghost.eyes:
+					ghost.frightSteps = self.levelRules()['GhostFrightSteps']
+					ghost.plannedDirection = reversedDirections[ghost.plannedDirection]
 
 	def wallAt(self, row: int, col: int) -> bool:
 		'''
@@ -821,9 +1072,14 @@ class GameState:
 
 		# Check for collisions
 		for ghost in self.ghosts:
-			if ghost.location.at(pacmanRow, pacmanCol):
+			if ghost.location.at(pacmanRow, pacmanCol) and not ghost.eyes:
 				if not ghost.isFrightened(): # Collision; Pacman loses
 					return False
+				elif self.rules['GhostEyes']: # Turn the ghost into eyes
+					ghost.eyes = True
+					ghost.eaten = True
+					ghost.frightSteps = 0
+					ghost.movePeriod = self.eyesMovePeriod()
 				else: # 'Respawn' the ghost
 					ghost.location.row = 32
 					ghost.location.col = 32
@@ -860,38 +1116,40 @@ class GameState:
 		# Loop over every tick
 		for tick in range(1, numTicks+1):
 
+			# Move any eyes on their own (faster) schedule, even between updates
+			for ghost in self.ghosts:
+				if ghost.eyes and (self.currTicks + tick) % ghost.movePeriod == 0:
+					ghost.move()
+					ghost.guessPlan()
+
 			# Keep ticking until an update
 			if (self.currTicks + tick) % self.updatePeriod != 0:
 				continue
 
 			# Update the ghost positions (and reduce frightened steps if applicable)
 			for ghost in self.ghosts:
-				ghost.move()
+				if not ghost.eyes:
+					ghost.move()
 
 			# Return if Pacman collides with a non-frightened ghost
 			if not self.safetyCheck():
 				return False
 
-			# Update the mode steps counter, and change the mode if necessary
-			if self.modeSteps > 0:
+			# Update the mode steps counter, unless the ghosts are angry
+			# (or the wave lasts forever)
+			if self.modeSteps > 0 and \
+				self.numPellets() >= self.rules['AngerThreshold1'] and \
+				(self.levelRules()['RepeatWaves'] or \
+				self.waveIdx < len(self.levelRules()['Waves'])):
 				self.modeSteps -= 1
 
+			# Move on to the next wave (changing the mode) if necessary
 			if self.modeSteps == 0:
-
-				# Scatter -> Chase
-				if self.gameMode == GameModes.SCATTER:
-					self.gameMode = GameModes.CHASE
-					self.modeSteps = 180
-					self.modeDuration = 180
-
-				# Chase -> Scatter
-				elif self.gameMode == GameModes.CHASE and self.numPellets() > 20:
-					self.gameMode = GameModes.SCATTER
-					self.modeSteps = 60
-					self.modeDuration = 60
+				self.nextWave()
 
 				# Reverse the planned directions of all ghosts
 				for ghost in self.ghosts:
+					if not ghost.eyes:
 						ghost.plannedDirection = reversedDirections[ghost.plannedDirection]
 
 			# Guess the next ghost moves (will likely be inaccurate, due to inferring
@@ -35,13 +35,23 @@ def getConnectURL() -> str:
 	# Return the websocket connect address
 	return f'ws://{config["ServerIP"]}:{config["WebSocketPort"]}'
 
+# Get whether the server uses the legacy protocol from the config.json file
+def getLegacyProtocol() -> bool:
+
+	# Read the configuration file
+	with open('../config.json', 'r', encoding='UTF-8') as configFile:
+		config = json.load(configFile)
+
+	# Return the legacy protocol flag (off by default)
+	return bool(config.get('LegacyProtocol', False))
+
 class PacbotClient:
 	'''
 	Sample implementation of a websocket client to communicate with the
 	Pacbot game server, using asyncio.
 	'''
 
-	def __init__(self, connectURL: str) -> None:
+	def __init__(self, connectURL: str, legacyProtocol: bool = False) -> None:
 		'''
 		Construct a new Pacbot client object
 		'''
@@ -56,7 +66,7 @@ class PacbotClient:
 		self.connection: ClientConnection
 
 		# Game state object to store the game information
-		self.state: GameState = GameState()
+		self.state: GameState = GameState(legacyProtocol)
 
 		# Decision module (policy) to make high-level decisions
 		self.decisionModule: DecisionModule = DecisionModule(self.state)
@@ -130,12 +140,19 @@ class PacbotClient:
 				# Receive a message from the connection
 				message: Data = self.connection.recv()
 
-				# Convert the message to bytes, if necessary
-				messageBytes: bytes
-				if isinstance(message, bytes):
-					messageBytes = message # type: ignore
-				else:
-					messageBytes = message.encode('ascii') # type: ignore
+				# Text messages hold server info (such as the rules and maze) or game events as JSON
+				if isinstance(message, str):
+					info = json.loads(message)
+					if 'Rules' in info:
+						self.state.updateRules(info['Rules'])
+					if 'Maze' in info:
+						self.state.updateMaze(info['Maze'])
+					if 'Event' in info:
+						print(f'Game event: {info["Event"]} (score = {info["Score"]}, lives = {info["Lives"]})')
+					continue
+
+				# Otherwise, the message is a serialized game state
+				messageBytes: bytes = message # type: ignore
 
 				# Update the state, given this message from the server
 				self.state.update(messageBytes)
@@ -159,7 +176,7 @@ async def main():
 
 	# Get the URL to connect to
 	connectURL = getConnectURL()
-	client = PacbotClient(connectURL)
+	client = PacbotClient(connectURL, getLegacyProtocol())
 	await client.run()
 
 	# Once the connection is closed, end the event loop
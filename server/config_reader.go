@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"log"
 	"os"
+	"pacbot_server/game"
 )
 
 type Configuration struct {
@@ -14,6 +15,17 @@ type Configuration struct {
 	GameFPS          int32
 	NumActiveGhosts  uint8
 	TrustedClientIPs []string
+	RandomSeed       *int64 // nil (or left out) for a seed from the clock
+	HeadlessMode     string
+	RecordingDir     string
+	RecordFrames     bool
+	LegacyProtocol   bool
+	MazeFile         string
+	GhostStrategies  map[string]string
+	RulesProfile     string
+
+	// The rules in force, filled in from the rules profile (kept in recordings)
+	Rules *game.Rules `json:",omitempty"`
 }
 
 // Read from the config.json file in the base directory
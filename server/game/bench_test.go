@@ -0,0 +1,80 @@
+package game
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+// Pacman movement commands, for driving games with random moves
+var benchMoves = [][]byte{[]byte("w"), []byte("a"), []byte("s"), []byte("d")}
+
+// Keep a simulated game going, restarting it once Pacman runs out of lives
+func benchKeepPlaying(sim *Simulator) {
+	if sim.Lives() == 0 {
+		sim.Reset(1)
+	}
+	if sim.state.isPaused() {
+		sim.Step([]byte("P"))
+	}
+}
+
+// Benchmark a simulator step, with Pacman making random moves
+func BenchmarkSimulatorStep(b *testing.B) {
+	sim := NewSimulator(1)
+	rng := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchKeepPlaying(sim)
+		sim.Step(benchMoves[rng.Intn(len(benchMoves))])
+	}
+}
+
+// Benchmark a game state update, followed by serializing a frame
+func BenchmarkUpdateSerialize(b *testing.B) {
+	sim := NewSimulator(1)
+	sim.Step([]byte("P"))
+	outputBuf := make([]byte, serBufSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if sim.Lives() == 0 {
+			sim.Reset(1)
+			sim.Step([]byte("P"))
+		}
+		sim.state.update()
+		sim.state.serFull(outputBuf, 0)
+	}
+}
+
+// Benchmark cloning a game state partway through a game
+func BenchmarkClone(b *testing.B) {
+	sim := NewSimulator(1)
+	sim.Step([]byte("P"))
+	for i := 0; i < 1000; i++ {
+		sim.Step()
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sim.state.clone()
+	}
+}
+
+// Benchmark a frame of a headless (fast-forward) game engine
+func BenchmarkEngineFrame(b *testing.B) {
+	inputCh := make(chan []byte, 1)
+	outputCh := make(chan []byte, 1)
+	ge := NewHeadlessGameEngine(outputCh, inputCh, &sync.WaitGroup{}, false)
+	rng := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if ge.state.getLives() == 0 {
+			inputCh <- []byte("r1")
+		} else if ge.state.isPaused() {
+			inputCh <- []byte("P")
+		} else {
+			inputCh <- benchMoves[rng.Intn(len(benchMoves))]
+		}
+		ge.runFrame()
+		<-outputCh
+	}
+}
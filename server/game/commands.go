@@ -2,6 +2,7 @@ package game
 
 import (
 	"log"
+	"strconv"
 )
 
 /***************************** Interpret Commands *****************************/
@@ -29,13 +30,17 @@ func (gs *gameState) interpretCommand(msg []byte) bool {
 	case 'P':
 		gs.play()
 
-	// Restart command
+	// Single-step command (play until the next update, then pause again)
+	case 'n':
+		gs.stepUpdate()
+
+	// Restart command (optionally followed by a decimal seed)
 	case 'r':
-		return true
+		return validResetSeed(msg)
 
-	// Restart command
+	// Restart command (optionally followed by a decimal seed)
 	case 'R':
-		return true
+		return validResetSeed(msg)
 
 	// Move up (decrease row index)
 	case 'w':
@@ -65,3 +70,41 @@ func (gs *gameState) interpretCommand(msg []byte) bool {
 
 	return false
 }
+
+/******************************** Reset Seeds *********************************/
+
+/*
+Determine whether a reset command carries a valid seed (or none at all),
+logging an error if the seed could not be parsed
+*/
+func validResetSeed(msg []byte) bool {
+
+	// No seed given, so the default seed will be used
+	if len(msg) == 1 {
+		return true
+	}
+
+	// Try to parse the seed as a decimal (signed 64-bit) integer
+	if _, err := strconv.ParseInt(string(msg[1:]), 10, 64); err != nil {
+		log.Printf("\033[35m\033[1mERR:  Invalid reset seed %q "+
+			"(message type '%c'). Ignoring...\033[0m\n", msg[1:], msg[0])
+		return false
+	}
+
+	// Otherwise, the seed is valid
+	return true
+}
+
+// Get the seed that a (valid) reset command requests for the next game
+func getResetSeed(msg []byte) int64 {
+
+	// If there's a seed following the command, use it
+	if len(msg) > 1 {
+		if seed, err := strconv.ParseInt(string(msg[1:]), 10, 64); err == nil {
+			return seed
+		}
+	}
+
+	// Otherwise, fall back to the default seed
+	return getDefaultSeed()
+}
@@ -0,0 +1,65 @@
+package game
+
+import (
+	"encoding/json"
+	"log"
+)
+
+/*
+A game event object, for something that happens in the game which clients
+should hear about as it happens (rather than by comparing frames) - events
+are sent to clients as JSON text messages
+*/
+type GameEvent struct {
+	Event string // The kind of event (see below)
+	Ticks uint32 // The tick at which the event happened
+	Score uint32 // The score when the event happened
+	Lives uint8  // The lives left after the event
+}
+
+// Kinds of game events
+const (
+	EventBonusLife = "BonusLife" // Pacman earned a bonus life
+)
+
+/*
+A function for the game engine to send each game event to (as JSON), or nil
+to drop them - called from the game engine's go-routine
+*/
+var eventHandler func(msg []byte)
+
+// Set the function that game events are sent to based on a configuration
+func ConfigEventHandler(_eventHandler func(msg []byte)) {
+	eventHandler = _eventHandler
+}
+
+// Record a game event, to be sent out with the next frame
+func (gs *gameState) emitEvent(event string) {
+	gs.events = append(gs.events, GameEvent{
+		Event: event,
+		Ticks: gs.getCurrTicks(),
+		Score: gs.getScore(),
+		Lives: gs.getLives(),
+	})
+}
+
+// Send the game events recorded since the last frame, then forget them
+func (ge *GameEngine) sendEvents() {
+
+	// Send each event in the order that it happened
+	for _, event := range ge.state.events {
+		if eventHandler == nil {
+			break
+		}
+		msg, err := json.Marshal(event)
+		if err != nil {
+			log.Printf("\033[35m\033[1mERR:  Failed to encode a game event "+
+				"(%s)\033[0m\n", err)
+			continue
+		}
+		eventHandler(msg)
+	}
+
+	// Clear the events, so they are only sent once
+	ge.state.events = nil
+}
@@ -1,7 +1,9 @@
 package game
 
 import (
+	"bytes"
 	"log"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -12,18 +14,36 @@ var activeGameEngines = 0
 // Mutex to protect numActiveGameEngines
 var muAGE sync.Mutex
 
+// Enum-like declaration to hold the clock modes of the game engine
+const (
+	clockRealTime    uint8 = 0 // Ticks are driven by a wall-clock ticker
+	clockFastForward uint8 = 1 // Ticks advance as fast as possible
+	clockStepped     uint8 = 2 // Ticks advance only on explicit step requests
+)
+
 /*
 A game engine object, to act as an intermediary between the web broker
 and the internal game state - its responsibility is to read responses from
-clients and routinely send serialized copies of the game state to them
+clients and routinely send serialized copies of the game state to them.
+
+The engine's go-routine is the sole owner of the game state: commands are
+applied to it between frames, and every frame sent out is a fresh, immutable
+snapshot of it, so no locking is needed anywhere in the game state
 */
 type GameEngine struct {
 	quitCh      chan struct{}
 	webOutputCh chan<- []byte
 	webInputCh  <-chan []byte
 	state       *gameState
-	ticker      *time.Ticker    // serves as the game clock
+	ticker      *time.Ticker    // serves as the game clock (nil if headless)
 	wgQuit      *sync.WaitGroup // wait group to make sure it quits safely
+	clockMode   uint8           // how the engine decides when to tick
+	justTicked  bool            // whether the last loop iteration was a tick
+	stepsLeft   int             // ticks requested so far (stepped mode only)
+	frameStale  bool            // whether a command changed the paused state
+	pendingMsgs [][]byte        // commands received while waiting (headless)
+	recorder    *matchRecorder  // records commands to a file (nil if disabled)
+	history     *stateHistory   // snapshots at each update, for rewinding
 }
 
 // Create a new game engine, casting channels to be uni-directional
@@ -36,15 +56,53 @@ func NewGameEngine(_webOutputCh chan<- []byte, _webInputCh <-chan []byte,
 		quitCh:      make(chan struct{}),
 		webOutputCh: _webOutputCh,
 		webInputCh:  _webInputCh,
-		state:       newGameState(),
+		state:       newGameState(getDefaultSeed()),
 		ticker:      time.NewTicker(_tickTime),
 		wgQuit:      _wgQuit,
+		clockMode:   clockRealTime,
+		justTicked:  true,
+		history:     newStateHistory(historyCapacity),
+	}
+
+	// Return the game engine
+	return &ge
+}
+
+/*
+Create a new headless game engine, which has no wall-clock ticker - it either
+advances ticks as fast as possible, or (if stepped) only when a client asks
+for more ticks with a step request ('t', optionally followed by a count)
+*/
+func NewHeadlessGameEngine(_webOutputCh chan<- []byte, _webInputCh <-chan []byte,
+	_wgQuit *sync.WaitGroup, stepped bool) *GameEngine {
+
+	// Pick the clock mode, depending on whether step requests are required
+	clockMode := clockFastForward
+	if stepped {
+		clockMode = clockStepped
+	}
+
+	ge := GameEngine{
+		quitCh:      make(chan struct{}),
+		webOutputCh: _webOutputCh,
+		webInputCh:  _webInputCh,
+		state:       newGameState(getDefaultSeed()),
+		ticker:      nil,
+		wgQuit:      _wgQuit,
+		clockMode:   clockMode,
+		justTicked:  true,
+		history:     newStateHistory(historyCapacity),
 	}
 
 	// Return the game engine
 	return &ge
 }
 
+// Determine whether the game engine runs without a wall-clock ticker
+func (ge *GameEngine) isHeadless() bool {
+	return ge.clockMode != clockRealTime
+}
+
 // Quit by closing the game engine, in case the loop ends
 func (ge *GameEngine) quit() {
 
@@ -55,7 +113,14 @@ func (ge *GameEngine) quit() {
 	ge.wgQuit.Done()
 
 	// Free up the ticker
-	ge.ticker.Stop()
+	if ge.ticker != nil {
+		ge.ticker.Stop()
+	}
+
+	// Close the match recording
+	if ge.recorder != nil {
+		ge.recorder.close(ge.state.getCurrTicks())
+	}
 }
 
 // Quit function exported to other packages
@@ -89,110 +154,274 @@ func (ge *GameEngine) RunLoop() {
 		return
 	}
 
-	// Output buffer to store the serialized output
-	outputBuf := make([]byte, 256)
+	// Log the seed of the first game, so that it can be reproduced
+	ge.state.logSeed()
+
+	for {
+
+		/* STEPS 1-6: Play out a frame of the game */
+		ge.runFrame()
+
+		/* STEP 7: Wait for the clock to allow the next frame */
+		if !ge.waitForClock() {
+			return
+		}
+	}
+}
+
+/******************************** Engine Steps ********************************/
+
+/*
+Play out a single frame of the game engine loop - update the game state (if
+it just ticked), send out a frame, handle any commands, and move on to the
+next tick (without waiting for the clock)
+*/
+func (ge *GameEngine) runFrame() {
+
+	/*
+		If the game did not just tick, we know it was paused, so we can skip
+		these steps as they were already done during the first paused tick
+	*/
+	if ge.justTicked && ge.state.updateReady() {
+
+		/* STEPS 1-2: Update the ghosts, then plan their next moves */
+		ge.state.update()
+
+		// Take a snapshot of the game state, for rewinding
+		ge.history.push(ge.state)
+	} else if ge.justTicked {
+
+		// Move any ghosts that are faster than the game's steps
+		ge.state.updateBetweenSteps()
+	}
+
+	/* STEP 3: Serialize the current game state to a new frame */
+	frame := ge.serializeFrame()
 
-	// Length of the serialized output
-	serLen := 0
+	/* STEP 4: Write the serialized game state to the output channel */
+	ge.writeFrame(frame)
 
-	// Flag to keep track of whether the last iteration of the loop was a tick
-	justTicked := true
+	// Send any game events that led up to this frame
+	ge.sendEvents()
 
+	// Record the frame, if necessary
+	if ge.recorder != nil {
+		ge.recorder.recordFrame(ge.state.getCurrTicks(), frame)
+	}
+
+	/* STEP 5: Read the input channel and update the game state accordingly */
+
+	// Handle any commands queued while waiting for the clock (headless only)
+	handled := len(ge.pendingMsgs)
+	for _, msg := range ge.pendingMsgs {
+		ge.handleCommand(msg)
+	}
+	ge.pendingMsgs = ge.pendingMsgs[:0]
+read_loop:
 	for {
+		select {
+		// If we get a message from the web broker, handle it
+		case msg := <-ge.webInputCh:
+			ge.handleCommand(msg)
+			handled++
+		default:
+			break read_loop
+		}
+	}
 
-		/*
-			If the game did not just tick, we know it was paused, so we can skip
-			these steps as they were already done during the first paused tick
-		*/
-		if justTicked && ge.state.updateReady() {
-			/* STEP 1: Update the ghost positions if necessary */
+	/*
+		If the commands changed the game state while paused (by pausing,
+		resetting or rewinding, for example), the frame just sent is out of
+		date, so send the next one without waiting for the clock (a fast-forward
+		engine would otherwise not send another one until the next command
+		arrives, and a stepped engine only sends the frames it is asked for)
+	*/
+	if handled > 0 && ge.state.isPaused() && ge.clockMode != clockStepped {
+		ge.frameStale = !bytes.Equal(ge.serializeFrame(), frame)
+	}
 
-			// Update all ghosts at once
-			ge.state.updateAllGhosts()
+	/* STEP 6: Update the game state for the next tick */
 
-			// Try to respawn Pacman (if it is at an empty location)
-			ge.state.tryRespawnPacman()
+	// Increment the number of ticks
+	if !ge.state.isPaused() {
+		ge.justTicked = true
+		ge.state.nextTick()
+	} else {
+		ge.justTicked = false
+	}
+}
 
-			// If we should pause upon updating, do so
-			if ge.state.getPauseOnUpdate() {
-				ge.state.pause()
-				ge.state.setPauseOnUpdate(false)
-			}
+/*
+Serialize the current game state into a new frame (frames are never written
+to again once sent, so the web broker can read them while the engine moves on)
+*/
+func (ge *GameEngine) serializeFrame() []byte {
 
-			// Check for collisions
-			ge.state.checkCollisions()
+	// Serialize the current state into a fresh buffer
+	outputBuf := make([]byte, serBufSize)
+	serLen := ge.state.serFull(outputBuf, 0)
 
-			/*
-				Decrement all step counters, and decide if the mode, penalty,
-				or fruit states should change
-			*/
-			ge.state.handleStepEvents()
+	// Return the serialized frame
+	return outputBuf[:serLen]
+}
 
-			/* STEP 2: Start planning the next ghost moves if an update happened */
+// Write a serialized frame to the output channel
+func (ge *GameEngine) writeFrame(frame []byte) {
+
+	// Check if a write will be blocked, and try to write the serialized state
+	b := len(ge.webOutputCh) == cap(ge.webOutputCh)
+	start := time.Now()
+	ge.webOutputCh <- frame
+
+	/*
+		If the write was blocked for too long (> 1ms), send a warning
+		to the terminal (headless engines are expected to block, though)
+	*/
+	if b && !ge.isHeadless() {
+		wait := time.Since(start)
+		if wait > time.Millisecond {
+			log.Printf("\033[35mWARN: The game engine output channel was "+
+				"full (%s)\033[0m\n", wait)
+		}
+	}
+}
 
-			// Plan the next ghost moves
-			ge.state.planAllGhosts()
+// Handle a message from the web broker
+func (ge *GameEngine) handleCommand(msg []byte) {
+
+	// Step requests go to the engine itself, rather than the game state
+	if msg[0] == 't' {
+		ge.requestSteps(msg)
+		return
+	}
+
+	// Keep track of the tick that the command is applied at
+	tick := ge.state.getCurrTicks()
+
+	// Rewind commands restore a snapshot from the state history
+	rst := false
+	if msg[0] == 'u' {
+		if restored := ge.history.interpretRewind(ge.state, msg); restored != nil {
+			ge.state = restored
+		}
+	} else { // Otherwise, let the game state interpret the command
+		rst = ge.state.interpretCommand(msg)
+		if rst { // Reset if necessary
+			ge.state = newResetGameState(getResetSeed(msg))
+			ge.history.clear()
+			ge.justTicked = true
 		}
+	}
 
-		/* STEP 3: Serialize the current game state to the output buffer */
+	// Record the command, if necessary
+	if ge.recorder != nil {
+		ge.recorder.recordCommand(tick, msg, rst, ge.state.getSeed())
+	}
+}
 
-		// Re-serialize the current state
-		serLen = ge.state.serFull(outputBuf, 0)
+/******************************* Engine Clocks ********************************/
 
-		/* STEP 4: Write the serialized game state to the output channel */
+/*
+Wait until the engine is allowed to process the next frame, depending on the
+clock mode - returns false if the engine should quit instead
+*/
+func (ge *GameEngine) waitForClock() bool {
 
-		// Check if a write will be blocked, and try to write the serialized state
-		b := len(ge.webOutputCh) == cap(ge.webOutputCh)
-		start := time.Now()
-		ge.webOutputCh <- outputBuf[:serLen]
+	// If the last frame is out of date, send the next one right away
+	if ge.frameStale {
+		ge.frameStale = false
+		select {
+		case <-ge.quitCh:
+			return false
+		default:
+			return true
+		}
+	}
 
-		/*
-			If the write was blocked for too long (> 1ms), send a warning
-			to the terminal
-		*/
-		if b {
-			wait := time.Since(start)
-			if wait > time.Millisecond {
-				log.Printf("\033[35mWARN: The game engine output channel was "+
-					"full (%s)\033[0m\n", wait)
-			}
+	switch ge.clockMode {
+
+	// Wait for the ticker to complete the current frame
+	case clockRealTime:
+		select {
+		case <-ge.ticker.C:
+			return true
+		// If we get a quit signal, quit this engine
+		case <-ge.quitCh:
+			return false
 		}
 
-		/* STEP 5: Read the input channel and update the game state accordingly */
-	read_loop:
-		for {
+	/*
+		Continue right away, unless paused (in which case, nothing would change
+		until the next command, so block until one arrives)
+	*/
+	case clockFastForward:
+		if !ge.state.isPaused() {
 			select {
-			// If we get a message from the web broker, handle it
-			case msg := <-ge.webInputCh:
-				rst := ge.state.interpretCommand(msg)
-				if rst { // Reset if necessary
-					ge.state = newGameState()
-					ge.state.updateAllGhosts()
-					ge.state.handleStepEvents()
-					ge.state.planAllGhosts()
-					justTicked = true
-				}
+			case <-ge.quitCh:
+				return false
 			default:
-				break read_loop
+				return true
 			}
 		}
+		return ge.waitForCommand()
 
-		/* STEP 6: Update the game state for the next tick */
+	// Block (queueing commands) until a step is requested
+	case clockStepped:
+		for ge.stepsLeft == 0 {
+			if !ge.waitForCommand() {
+				return false
+			}
+		}
+		ge.stepsLeft--
+		return true
+	}
 
-		// Increment the number of ticks
-		if !ge.state.isPaused() {
-			justTicked = true
-			ge.state.nextTick()
+	return true
+}
+
+/*
+Block until a command arrives - step requests are handled right away, while
+other commands are queued until the next frame reads its input (so that they
+apply at the same point in the loop as they would in real time). Returns
+false if the engine should quit instead
+*/
+func (ge *GameEngine) waitForCommand() bool {
+	select {
+	case msg := <-ge.webInputCh:
+		if msg[0] == 't' {
+			ge.requestSteps(msg)
 		} else {
-			justTicked = false
+			ge.pendingMsgs = append(ge.pendingMsgs, msg)
 		}
+		return true
+	// If we get a quit signal, quit this engine
+	case <-ge.quitCh:
+		return false
+	}
+}
 
-		/* STEP 5: Wait for the ticker to complete the current frame */
-		select {
-		case <-ge.ticker.C:
-		// If we get a quit signal, quit this broker
-		case <-ge.quitCh:
-			return
-		}
+// Add the number of ticks requested by a step request ('t', then a count)
+func (ge *GameEngine) requestSteps(msg []byte) {
+
+	// Step requests only make sense if the engine is stepped
+	if ge.clockMode != clockStepped {
+		log.Println("\033[35mWARN: Step request received, but the game " +
+			"engine is not stepped. Ignoring...\033[0m")
+		return
+	}
+
+	// A step request without a count is for a single tick
+	if len(msg) == 1 {
+		ge.stepsLeft++
+		return
+	}
+
+	// Otherwise, parse the (decimal) count of ticks
+	steps, err := strconv.Atoi(string(msg[1:]))
+	if err != nil || steps <= 0 {
+		log.Printf("\033[35m\033[1mERR:  Invalid step count %q "+
+			"(message type 't'). Ignoring...\033[0m\n", msg[1:])
+		return
 	}
+	ge.stepsLeft += steps
 }
@@ -0,0 +1,104 @@
+package game
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+Wait for a game engine's clock (failing the test if it blocks for too long),
+then play out a frame, returning the frame it sent
+*/
+func nextEngineFrame(t *testing.T, ge *GameEngine,
+	outputCh <-chan []byte) []byte {
+	t.Helper()
+
+	// Wait for the clock in the background, so that blocking can be caught
+	done := make(chan bool, 1)
+	go func() { done <- ge.waitForClock() }()
+	select {
+	case ok := <-done:
+		if !ok {
+			t.Fatal("engine quit while waiting for its clock")
+		}
+	case <-time.After(500 * time.Millisecond):
+		ge.Quit()
+		<-done
+		t.Fatal("engine waited for its clock instead of sending a new frame")
+	}
+
+	// Play out the frame
+	ge.runFrame()
+	return <-outputCh
+}
+
+/*
+Check that a paused fast-forward engine sends a new frame as soon as a command
+changes the game state (by pausing, rewinding or resetting), without waiting
+for another command to arrive
+*/
+func TestFastForwardEngineSendsCommandFrames(t *testing.T) {
+
+	// Start a fast-forward engine, and play for a while
+	inputCh := make(chan []byte, 10)
+	outputCh := make(chan []byte, 1)
+	ge := NewHeadlessGameEngine(outputCh, inputCh, &sync.WaitGroup{}, false)
+	inputCh <- []byte("P")
+	ge.runFrame()
+	<-outputCh
+	for frame := 0; frame < 300; frame++ {
+		nextEngineFrame(t, ge, outputCh)
+	}
+
+	// Pause - the frame after the pause should show it
+	inputCh <- []byte("p")
+	nextEngineFrame(t, ge, outputCh)
+	frame := nextEngineFrame(t, ge, outputCh)
+	if frame[serGameModeIdx()] != paused {
+		t.Fatalf("frame after pausing is not paused (mode %d)",
+			frame[serGameModeIdx()])
+	}
+	if !bytes.Equal(frame, ge.serializeFrame()) {
+		t.Fatal("frame after pausing differs from the game state")
+	}
+
+	// Rewind - the frame after the rewind should show the earlier tick
+	ticks := ge.state.getCurrTicks()
+	inputCh <- []byte("u2")
+	nextEngineFrame(t, ge, outputCh)
+	frame = nextEngineFrame(t, ge, outputCh)
+	if ge.state.getCurrTicks() >= ticks {
+		t.Fatalf("rewind did not go back (t = %d -> %d)",
+			ticks, ge.state.getCurrTicks())
+	}
+	if !bytes.Equal(frame, ge.serializeFrame()) {
+		t.Fatal("frame after rewinding differs from the game state")
+	}
+
+	// Reset with a seed - the frame after the reset should show the new game
+	inputCh <- []byte("r5")
+	nextEngineFrame(t, ge, outputCh)
+	frame = nextEngineFrame(t, ge, outputCh)
+	if ge.state.getSeed() != 5 || ge.state.getCurrTicks() != 0 {
+		t.Fatalf("reset game has seed %d at t = %d, expected seed 5 at t = 0",
+			ge.state.getSeed(), ge.state.getCurrTicks())
+	}
+	if !bytes.Equal(frame, ge.serializeFrame()) {
+		t.Fatal("frame after resetting differs from the game state")
+	}
+
+	// With nothing new to send, the paused engine should wait for a command
+	done := make(chan bool, 1)
+	go func() { done <- ge.waitForClock() }()
+	select {
+	case <-done:
+		t.Fatal("paused engine did not wait for a command")
+	case <-time.After(50 * time.Millisecond):
+	}
+	ge.Quit()
+	if <-done {
+		t.Fatal("engine did not quit while waiting for a command")
+	}
+}
@@ -44,13 +44,80 @@ func (gs *gameState) updateReady() bool {
 	// Get the current ticks value
 	currTicks := gs.getCurrTicks()
 
-	// Get the update period (uint16 to match the type of current ticks)
-	updatePeriod := uint16(gs.getUpdatePeriod())
+	// Get the update period (uint32 to match the type of current ticks)
+	updatePeriod := uint32(gs.getUpdatePeriod())
 
 	// Update if the update period divides the current ticks
 	return currTicks%updatePeriod == 0
 }
 
+// Update the game state by one step (once the update period has elapsed)
+func (gs *gameState) update() {
+
+	// Find the ghosts that move at this step (before any speeds change)
+	movers := gs.readyGhosts()
+
+	/* STEP 1: Update the ghost positions if necessary */
+
+	// Update the moving ghosts at once
+	gs.updateGhosts(movers)
+
+	// Count down the frightened steps of all the ghosts, moving or not
+	gs.decrementAllFrightSteps()
+
+	// Try to respawn Pacman (if it is at an empty location)
+	gs.tryRespawnPacman()
+
+	// If we should pause upon updating, do so
+	if gs.getPauseOnUpdate() {
+		gs.pause()
+		gs.setPauseOnUpdate(false)
+	}
+
+	// Any board reset (after Pacman died or cleared a level) is now done
+	gs.resetPending = false
+
+	// Check for collisions
+	gs.checkCollisions()
+
+	/*
+		Decrement all step counters, and decide if the mode, penalty,
+		or fruit states should change
+	*/
+	gs.handleStepEvents()
+
+	/* STEP 2: Start planning the next ghost moves if an update happened */
+
+	// Plan the next moves of the ghosts that moved
+	gs.planGhosts(movers)
+}
+
+/*
+Update the ghosts that move between the game's steps (those faster than the
+game's update period), if any move at the current tick
+*/
+func (gs *gameState) updateBetweenSteps() {
+
+	/*
+		Ghosts stay put until the next update after a board reset (when Pacman
+		dies or clears a level), so they don't leave their spawns early
+	*/
+	if gs.resetPending {
+		return
+	}
+
+	// Find the ghosts that move at this tick, if any
+	movers := gs.readyGhosts()
+	if movers == 0 {
+		return
+	}
+
+	// Move them, check for collisions, and plan their next moves
+	gs.updateGhosts(movers)
+	gs.checkCollisions()
+	gs.planGhosts(movers)
+}
+
 /**************************** Positional Functions ****************************/
 
 // Determines if a position is within the bounds of the maze
@@ -58,16 +125,40 @@ func (gs *gameState) inBounds(row int8, col int8) bool {
 	return ((row >= 0 && row < mazeRows) && (col >= 0 && col < mazeCols))
 }
 
+/*
+Wrap a location just off the edge of the maze around to the other side,
+if it leads through a warp portal (see maze.go)
+*/
+func (gs *gameState) wrapCoords(row int8, col int8) (int8, int8) {
+	return gs.maze.wrapCoords(row, col)
+}
+
+// Determines if a tunnel (or warp portal) is at a given location
+func (gs *gameState) tunnelAt(row int8, col int8) bool {
+	if !gs.inBounds(row, col) {
+		return false
+	}
+
+	// Returns the bit of the tunnel row corresponding to the column
+	return getBit(gs.maze.tunnels[row], col)
+}
+
+// Determines if ghosts are forbidden from turning upwards at a given location
+func (gs *gameState) noUpTurnAt(row int8, col int8) bool {
+	if !gs.inBounds(row, col) {
+		return false
+	}
+
+	// Returns the bit of the restricted turn row corresponding to the column
+	return getBit(gs.maze.noUpTurns[row], col)
+}
+
 // Determines if a pellet is at a given location
 func (gs *gameState) pelletAt(row int8, col int8) bool {
 	if !gs.inBounds(row, col) {
 		return false
 	}
 
-	// (Read) lock the pellets array
-	gs.muPellets.RLock()
-	defer gs.muPellets.RUnlock()
-
 	// Returns the bit of the pellet row corresponding to the column
 	return getBit(gs.pellets[row], col)
 }
@@ -81,7 +172,7 @@ func (gs *gameState) collectPellet(row int8, col int8) {
 	// Collect fruit, if applicable
 	if gs.fruitExists() && gs.pacmanLoc.collidesWith(gs.fruitLoc) {
 		gs.setFruitSteps(0)
-		gs.incrementScore(fruitPoints)
+		gs.incrementScore(gs.getFruit().Points)
 	}
 
 	// If there's no pellet, return
@@ -93,8 +184,11 @@ func (gs *gameState) collectPellet(row int8, col int8) {
 	modifyBit(&(gs.pellets[row]), col, false)
 	gs.decrementNumPellets()
 
-	// If the we are in particular rows and columns, it is a super pellet
-	superPellet := ((row == 3) || (row == 23)) && ((col == 1) || (col == 26))
+	// Count the pellet towards releasing the next ghost from the ghost house
+	gs.countReleasePellet()
+
+	// If the maze has a super pellet here, it is a super pellet
+	superPellet := gs.superPelletAt(row, col)
 
 	// Make all the ghosts frightened if a super pellet is collected
 	if superPellet {
@@ -103,36 +197,52 @@ func (gs *gameState) collectPellet(row int8, col int8) {
 
 	// Update the score, depending on the pellet type
 	if superPellet {
-		gs.incrementScore(superPelletPoints)
+		gs.incrementScore(gs.rules.SuperPelletPoints)
 	} else {
-		gs.incrementScore(pelletPoints)
+		gs.incrementScore(gs.rules.PelletPoints)
 	}
 
 	// Act depending on the number of pellets left over
 	numPellets := gs.getNumPellets()
 
 	// Spawn fruit, if applicable
-	if (numPellets == fruitThreshold1) && !gs.fruitExists() {
-		gs.setFruitSteps(fruitDuration)
-	} else if (numPellets == fruitThreshold2) && !gs.fruitExists() {
-		gs.setFruitSteps(fruitDuration)
+	for _, threshold := range gs.getFruit().Thresholds {
+		if (numPellets == threshold) && !gs.fruitExists() {
+			gs.spawnFruit()
+		}
 	}
 
 	// Other pellet-related events
-	if numPellets == angerThreshold1 { // Ghosts get angry (speeding up)
-		gs.setUpdatePeriod(uint8(max(1, int(gs.getUpdatePeriod())-2)))
-		gs.setMode(chase)
-		gs.setModeSteps(modeDurations[chase])
-	} else if numPellets == angerThreshold2 { // Ghosts get angrier
-		gs.setUpdatePeriod(uint8(max(1, int(gs.getUpdatePeriod())-2)))
-		gs.setMode(chase)
-		gs.setModeSteps(modeDurations[chase])
+	if numPellets == gs.rules.AngerThreshold1 { // Ghosts get angry (speeding up)
+		gs.angerGhosts(1)
+	} else if numPellets == gs.rules.AngerThreshold2 { // Ghosts get angrier
+		gs.angerGhosts(2)
 	} else if numPellets == 0 {
-		gs.levelReset()
 		gs.incrementLevel()
+		gs.levelReset()
 	}
 }
 
+/*
+Make the ghosts angry (or angrier, at the second stage) once few enough
+pellets are left - with Cruise Elroy, only red speeds up, while otherwise all
+the ghosts speed up and start chasing
+*/
+func (gs *gameState) angerGhosts(stage uint8) {
+
+	// With Cruise Elroy, move red on to the next stage of its speed-up
+	if gs.rules.CruiseElroy {
+		log.Printf("\033[31mGAME: Cruise Elroy (stage %d) (t = %d)\033[0m\n",
+			stage, gs.getCurrTicks())
+		gs.elroyStage = stage
+		return
+	}
+
+	// Otherwise, speed up the whole game, and make the ghosts chase
+	gs.setUpdatePeriod(uint8(max(1, int(gs.getUpdatePeriod())-2)))
+	gs.startWave(gs.levelRules.chaseWave(gs.getWave()))
+}
+
 // Determines if a wall is at a given location
 func (gs *gameState) wallAt(row int8, col int8) bool {
 	if !gs.inBounds(row, col) {
@@ -140,17 +250,33 @@ func (gs *gameState) wallAt(row int8, col int8) bool {
 	}
 
 	// Returns the bit of the wall row corresponding to the column
-	return getBit(gs.walls[row], col)
+	return getBit(gs.maze.walls[row], col)
+}
+
+// Determines if a super pellet is (or was, if collected) at a given location
+func (gs *gameState) superPelletAt(row int8, col int8) bool {
+	if !gs.inBounds(row, col) {
+		return false
+	}
+
+	// Returns the bit of the super pellet row corresponding to the column
+	return getBit(gs.maze.superPellets[row], col)
 }
 
 // Determines if the ghost house is at a given location
-func (gs *gameState) ghostSpawnAt(row int8, col int8) bool {
+func (gs *gameState) ghostHouseAt(row int8, col int8) bool {
 	if !gs.inBounds(row, col) {
 		return false
 	}
 
-	// Returns the bit of the wall row corresponding to the column
-	return ((row >= 13) && (row <= 14)) && ((col >= 11) && (col <= 15))
+	// Returns the bit of the ghost house row corresponding to the column
+	return getBit(gs.maze.ghostHouse[row], col)
+}
+
+// Determines if the ghost house exit is at a given location
+func (gs *gameState) ghostHouseExitAt(row int8, col int8) bool {
+	exitRow, exitCol := gs.maze.ghostHouseExit.getCoords()
+	return row == exitRow && col == exitCol
 }
 
 // Calculates the squared Euclidean distance between two points
@@ -198,8 +324,8 @@ func (gs *gameState) checkCollisions() {
 		return
 	}
 
-	// Lock the motion mutex to synchronize with other ghost update routines
-	gs.respawnGhosts(numGhostRespawns, ghostRespawnFlag)
+	// Respawn the eaten ghosts
+	gs.respawnGhosts(ghostRespawnFlag)
 }
 
 /***************************** Event-Based Resets *****************************/
@@ -207,12 +333,9 @@ func (gs *gameState) checkCollisions() {
 // Reset the board (while leaving pellets alone) after Pacman dies
 func (gs *gameState) deathReset() {
 
-	// Acquire the Pacman control lock, to prevent other Pacman movement
-	gs.muPacman.Lock()
-	defer gs.muPacman.Unlock()
-
 	// Set the game to be paused at the next update
 	gs.setPauseOnUpdate(true)
+	gs.resetPending = true
 
 	// Set Pacman to be in an empty state
 	gs.pacmanLoc.copyFrom(emptyLoc)
@@ -220,18 +343,20 @@ func (gs *gameState) deathReset() {
 	// Decrease the number of lives Pacman has left
 	gs.decrementLives()
 
-	/*
-		If the mode is not the initial mode and the ghosts aren't angry,
-		change the mode back to the initial mode
-	*/
-	if gs.getNumPellets() > angerThreshold1 {
-		gs.setMode(initMode)
-		gs.setModeSteps(modeDurations[initMode])
+	// If the ghosts aren't angry (or only red is), start the mode schedule over
+	if gs.rules.CruiseElroy || gs.getNumPellets() > gs.rules.AngerThreshold1 {
+		gs.startWave(0)
 	}
 
 	// Set the fruit steps back to 0
 	gs.setFruitSteps(0)
 
+	// Count pellets with the global counter until the ghosts are released
+	if gs.rules.PelletRelease {
+		gs.globalCounterActive = true
+		gs.globalPelletCounter = 0
+	}
+
 	// Reset all the ghosts to their original locations
 	gs.resetAllGhosts()
 }
@@ -241,20 +366,29 @@ func (gs *gameState) levelReset() {
 
 	// Set the game to be paused at the next update
 	gs.setPauseOnUpdate(true)
+	gs.resetPending = true
 
 	// Set Pacman to be in an empty state
 	gs.pacmanLoc.copyFrom(emptyLoc)
 
-	// If the mode is not the initial mode, change it
-	gs.setMode(initMode)
-	gs.setModeSteps(modeDurations[initMode])
+	// Start the level at the update period (ghost speed) from its rules
+	gs.setUpdatePeriod(gs.levelRules.UpdatePeriod)
+
+	// Start the level's mode schedule over
+	gs.startWave(0)
+
+	// Red starts the level at its normal speed
+	gs.elroyStage = 0
 
 	// Reset the level penalty
-	gs.setLevelSteps(levelDuration)
+	gs.setLevelSteps(gs.rules.LevelDuration)
 
 	// Set the fruit steps back to 0
 	gs.setFruitSteps(0)
 
+	// Start the pellet counters over for the new level
+	gs.resetReleaseCounters()
+
 	// Reset all the ghosts to their original locations
 	gs.resetAllGhosts()
 
@@ -264,42 +398,57 @@ func (gs *gameState) levelReset() {
 
 /************************** Motion (Pacman Location) **************************/
 
-// Move Pacman one space in a given direction
+/*
+Move Pacman one space in a given direction, from a manual (w/a/s/d) command -
+these are limited to one move per move period (for this level's speed)
+*/
 func (gs *gameState) movePacmanDir(dir uint8) {
 
-	// Acquire the Pacman control lock, to prevent other Pacman movement
-	gs.muPacman.Lock()
-	defer func() {
-
-		// Unlock when we return
-		gs.muPacman.Unlock()
-
-		// Check collisions with all the ghosts
-		gs.checkCollisions()
-	}()
+	// Check collisions with all the ghosts once we return
+	defer gs.checkCollisions()
 
 	// Ignore the command if the game is paused
 	if gs.isPaused() || gs.getPauseOnUpdate() {
 		return
 	}
 
+	// Ignore the command if Pacman moved too recently (for this level's speed)
+	if gs.getCurrTicks() < gs.pacmanMoveTick {
+		return
+	}
+
+	// Move Pacman, and start the move period over if it moved
+	if gs.stepPacman(dir) {
+		gs.pacmanMoveTick = gs.getCurrTicks() +
+			uint32(gs.levelRules.PacmanMovePeriod)
+	}
+}
+
+/*
+Move Pacman one space in a given direction (if there's no wall in the way),
+returning whether it moved - the move period is not checked, since tracking
+updates report where Pacman already is, and should never be held back
+*/
+func (gs *gameState) stepPacman(dir uint8) bool {
+
 	// Shorthand to make computation simpler
 	pLoc := gs.pacmanLoc
 
-	// Calculate the next row and column
-	nextRow, nextCol := pLoc.getNeighborCoords(dir)
+	// Calculate the next row and column (wrapping around through portals)
+	nextRow, nextCol := gs.wrapCoords(pLoc.getNeighborCoords(dir))
 
 	// Update Pacman's direction
 	pLoc.updateDir(dir)
 
 	// Check if there is a wall at the anticipated location, and return if so
 	if gs.wallAt(nextRow, nextCol) {
-		return
+		return false
 	}
 
 	// Move Pacman the anticipated spot
 	pLoc.updateCoords(nextRow, nextCol)
 	gs.collectPellet(nextRow, nextCol)
+	return true
 }
 
 // Move pacman to destination along shortest path (CV update)
@@ -335,15 +484,8 @@ func (gs *gameState) movePacmanAbsolute(newRow, newCol int8) {
 		log.Println("\033[35mWARN: Interpolated path too long! " +
 			"Tracking performance is likely degraded\033[0m")
 
-		// Acquire the Pacman control lock, to prevent other Pacman movement
-		gs.muPacman.Lock()
-		defer func() {
-			// Unlock when we return
-			gs.muPacman.Unlock()
-
-			// Check collisions with all the ghosts
-			gs.checkCollisions()
-		}()
+		// Check collisions with all the ghosts once we return
+		defer gs.checkCollisions()
 
 		// Move Pacman directly to the given position
 		pLoc.updateCoords(newRow, newCol)
@@ -356,29 +498,29 @@ func (gs *gameState) movePacmanAbsolute(newRow, newCol int8) {
 	// Move Pacman along the detected route
 	for i := range path {
 		nextPos := path[i]
-		if nextPos.r < prevPos.r {
-			gs.movePacmanDir(up)
-			gs.checkCollisions()
-			gs.collectPellet(gs.pacmanLoc.getCoords())
-		} else if nextPos.c < prevPos.c {
-			gs.movePacmanDir(left)
-			gs.checkCollisions()
-			gs.collectPellet(gs.pacmanLoc.getCoords())
-		} else if nextPos.r > prevPos.r {
-			gs.movePacmanDir(down)
-			gs.checkCollisions()
-			gs.collectPellet(gs.pacmanLoc.getCoords())
-		} else {
-			gs.movePacmanDir(right)
-			gs.checkCollisions()
-			gs.collectPellet(gs.pacmanLoc.getCoords())
-		}
+		gs.stepPacman(gs.dirBetween(prevPos, nextPos))
+		gs.checkCollisions()
+		gs.collectPellet(gs.pacmanLoc.getCoords())
 		prevPos = nextPos
 	}
 }
 
 type pos struct{ r, c int8 }
 
+/*
+Find the direction of a move between two neighboring positions (which may
+wrap around through a warp portal)
+*/
+func (gs *gameState) dirBetween(from pos, to pos) uint8 {
+	for dir := uint8(0); dir < numDirs; dir++ {
+		row, col := gs.wrapCoords(from.r+dRow[dir], from.c+dCol[dir])
+		if row == to.r && col == to.c {
+			return dir
+		}
+	}
+	return none
+}
+
 func (p pos) getAdjacent() [4]pos {
 	return [...]pos{
 		{p.r + 1, p.c},
@@ -389,7 +531,6 @@ func (p pos) getAdjacent() [4]pos {
 }
 
 // Find likely/shortest path to new coords
-// precondition: lock pacman pos
 func (gs *gameState) findLikelyPath(newRow, newCol int8) []pos {
 	// Begin breadth-first search
 	start := pos{gs.pacmanLoc.row, gs.pacmanLoc.col}
@@ -412,6 +553,9 @@ search_loop:
 		for i := range neighbors {
 			adj := neighbors[i]
 
+			// Wrap around through warp portals
+			adj.r, adj.c = gs.wrapCoords(adj.r, adj.c)
+
 			// Already searched this one, continue
 			if _, ok := parent[adj]; ok {
 				continue
@@ -451,13 +595,10 @@ search_loop:
 
 // Move Pacman back to its spawn point, if necessary
 func (gs *gameState) tryRespawnPacman() {
-	// Acquire the Pacman control lock, to prevent other Pacman movement
-	gs.muPacman.Lock()
-	defer gs.muPacman.Unlock()
 
 	// Set Pacman to be in its original state
 	if gs.pacmanLoc.isEmpty() && gs.getLives() > 0 {
-		gs.pacmanLoc.copyFrom(pacmanSpawnLoc)
+		gs.pacmanLoc.copyFrom(gs.maze.pacmanSpawn)
 	}
 }
 
@@ -466,21 +607,22 @@ func (gs *gameState) tryRespawnPacman() {
 // Frighten all ghosts at once
 func (gs *gameState) frightenAllGhosts() {
 
-	// Acquire the ghost control lock, to prevent other ghost movement decisions
-	gs.muGhosts.Lock()
-	defer gs.muGhosts.Unlock()
-
 	// Reset the ghost respawn combo back to 0
 	gs.ghostCombo = 0
 
 	// Loop over all the ghosts
 	for _, ghost := range gs.ghosts {
 
+		// Skip ghosts returning to the ghost house as eyes
+		if ghost.isEyes() {
+			continue
+		}
+
 		/*
 			To frighten a ghost, set its fright steps to a specified value
 			and trap it for one step (to force the direction to reverse)
 		*/
-		ghost.setFrightSteps(ghostFrightSteps)
+		ghost.setFrightSteps(gs.levelRules.GhostFrightSteps)
 		if !ghost.isTrapped() {
 			ghost.setTrappedSteps(1)
 		}
@@ -493,6 +635,11 @@ func (gs *gameState) reverseAllGhosts() {
 	// Loop over all the ghosts
 	for _, ghost := range gs.ghosts {
 
+		// Skip ghosts returning to the ghost house as eyes
+		if ghost.isEyes() {
+			continue
+		}
+
 		/*
 			To change the direction a ghost, trap it for one step
 			(to force the direction to reverse)
@@ -506,23 +653,17 @@ func (gs *gameState) reverseAllGhosts() {
 // Reset all ghosts at once
 func (gs *gameState) resetAllGhosts() {
 
-	// Acquire the ghost control lock, to prevent other ghost movement
-	gs.muGhosts.Lock()
-	defer gs.muGhosts.Unlock()
-
 	// Reset the ghost respawn combo back to 0
 	gs.ghostCombo = 0
 
-	// Add relevant ghosts to a wait group
-	gs.wgGhosts.Add(int(numColors))
-
 	// Reset each of the ghosts
 	for _, ghost := range gs.ghosts {
-		go ghost.reset()
+		ghost.reset()
 	}
 
-	// Wait for the resets to finish
-	gs.wgGhosts.Wait()
+	// Release any ghosts that the pellet counters allow out right away
+	gs.releaseSteps = 0
+	gs.releaseGhosts()
 
 	// If no lives are left, set all ghosts to stare at the player, menacingly
 	if gs.getLives() == 0 {
@@ -537,15 +678,7 @@ func (gs *gameState) resetAllGhosts() {
 }
 
 // Respawn some ghosts, according to a flag
-func (gs *gameState) respawnGhosts(
-	numGhostRespawns int, ghostRespawnFlag uint8) {
-
-	// Acquire the ghost control lock, to prevent other ghost movement
-	gs.muGhosts.Lock()
-	defer gs.muGhosts.Unlock()
-
-	// Add relevant ghosts to a wait group
-	gs.wgGhosts.Add(numGhostRespawns)
+func (gs *gameState) respawnGhosts(ghostRespawnFlag uint8) {
 
 	// Loop over the ghost colors again, to decide which should respawn
 	for _, ghost := range gs.ghosts {
@@ -557,128 +690,167 @@ func (gs *gameState) respawnGhosts(
 			ghost.respawn()
 
 			// Add points corresponding to the current combo length
-			gs.incrementScore(comboMultiplier << uint16(gs.ghostCombo))
+			gs.incrementScore(gs.rules.ComboMultiplier << uint16(gs.ghostCombo))
 
 			// Increment the ghost respawn combo
 			gs.ghostCombo++
 		}
 	}
+}
 
-	// Wait for the respawns to finish
-	gs.wgGhosts.Wait()
+// Get a flag of the ghosts that move at the current tick
+func (gs *gameState) readyGhosts() uint8 {
+	var flag uint8 = 0
+	for _, ghost := range gs.ghosts {
+		modifyBit(&flag, ghost.color, ghost.moveReady())
+	}
+	return flag
 }
 
 // Update all ghosts at once
 func (gs *gameState) updateAllGhosts() {
+	gs.updateGhosts(0xff)
+	gs.decrementAllFrightSteps()
+}
 
-	// Acquire the ghost control lock, to prevent other ghost movement
-	gs.muGhosts.Lock()
-	defer gs.muGhosts.Unlock()
-
-	// Add relevant ghosts to a wait group
-	gs.wgGhosts.Add(int(numColors))
+// Update some ghosts, according to a flag
+func (gs *gameState) updateGhosts(ghostFlag uint8) {
 
 	// Loop over the individual ghosts
 	for _, ghost := range gs.ghosts {
-		go ghost.update()
+		if getBit(ghostFlag, ghost.color) {
+			ghost.update()
+		}
 	}
+}
 
-	// Wait for the respawns to finish
-	gs.wgGhosts.Wait()
+// Decrement the frightened steps of all the (frightened) ghosts, once per step
+func (gs *gameState) decrementAllFrightSteps() {
+	for _, ghost := range gs.ghosts {
+		if ghost.isFrightened() {
+			ghost.decFrightSteps()
+		}
+	}
 }
 
 // A game state function to plan all ghosts at once
 func (gs *gameState) planAllGhosts() {
+	gs.planGhosts(0xff)
+}
 
-	// Acquire the ghost control lock, to prevent other ghost movement
-	gs.muGhosts.Lock()
-	defer gs.muGhosts.Unlock()
-
-	// Add pending ghost plans
-	gs.wgGhosts.Add(int(numColors))
+// Plan the next moves of some ghosts, according to a flag
+func (gs *gameState) planGhosts(ghostFlag uint8) {
 
-	// Plan each ghost's next move concurrently
+	/*
+		Plan each ghost's next move in turn (plans only read the current
+		locations, so the order doesn't matter)
+	*/
 	for _, ghost := range gs.ghosts {
-		go ghost.plan()
+		if getBit(ghostFlag, ghost.color) {
+			ghost.plan()
+		}
 	}
-
-	// Wait until all pending ghost plans are complete
-	gs.wgGhosts.Wait()
 }
 
-/************************ Ghost Targeting (Chase Mode) ************************/
+/******************************** Ghost Release *******************************/
 
 /*
-Returns the chase location of the red ghost
-(i.e. Pacman's exact location)
+Get the next ghost to leave the ghost house (the first one held, in order of
+color), or nil if no ghosts are held
 */
-func (gs *gameState) getChaseTargetRed() (int8, int8) {
-
-	// Return Pacman's current location
-	return gs.pacmanLoc.getCoords()
+func (gs *gameState) nextHeldGhost() *ghostState {
+	for _, ghost := range gs.ghosts {
+		if ghost.isHeld() {
+			return ghost
+		}
+	}
+	return nil
 }
 
 /*
-Returns the chase location of the pink ghost
-(i.e. 4 spaces ahead of Pacman's location)
+Release the held ghosts (in order of color) that have waited for enough
+pellets - by the global counter if Pacman died this level, or by their own
+counters otherwise
 */
-func (gs *gameState) getChaseTargetPink() (int8, int8) {
+func (gs *gameState) releaseGhosts() {
+
+	// Keep releasing ghosts until one needs to wait for more pellets
+	for ghost := gs.nextHeldGhost(); ghost != nil; ghost = gs.nextHeldGhost() {
+
+		// The global counter releases each ghost at its own limit
+		if gs.globalCounterActive {
+			if gs.globalPelletCounter < gs.rules.GlobalReleasePellets[ghost.color] {
+				return
+			}
 
-	// Return the red pink's target (4 spaces ahead of Pacman)
-	return gs.pacmanLoc.getAheadCoords(4)
+			/*
+				As in the arcade game, reaching orange's limit stops the global
+				counter rather than releasing orange, and it goes on waiting
+				for its own counter
+			*/
+			if ghost.color != orange {
+				ghost.setHeld(false)
+				continue
+			}
+			gs.globalCounterActive = false
+		}
+
+		// Otherwise, the ghost leaves once its own counter reaches its limit
+		if ghost.getPelletCounter() < gs.levelRules.ReleasePellets[ghost.color] {
+			return
+		}
+		ghost.setHeld(false)
+	}
 }
 
-/*
-Returns the chase location of the cyan ghost
-(i.e. The red ghost's location, reflected about 2 spaces ahead of Pacman)
-*/
-func (gs *gameState) getChaseTargetCyan() (int8, int8) {
+// Count a pellet towards releasing the next ghost from the ghost house
+func (gs *gameState) countReleasePellet() {
 
-	// Get the 'pivot' square, 2 steps ahead of Pacman
-	pivotRow, pivotCol := gs.pacmanLoc.getAheadCoords(2)
+	// If pellets don't release the ghosts, there's nothing to count
+	if !gs.rules.PelletRelease {
+		return
+	}
+
+	// Eating a pellet starts the release timer over
+	gs.releaseSteps = 0
 
-	// Get the current location of the red ghost
-	redRow, redCol := gs.ghosts[red].loc.getCoords()
+	// Count the pellet with the global counter, or the next ghost's own counter
+	if gs.globalCounterActive {
+		gs.globalPelletCounter = uint8(min(int(gs.globalPelletCounter)+1, 255))
+	} else if ghost := gs.nextHeldGhost(); ghost != nil {
+		ghost.incPelletCounter()
+	}
 
-	// Return the pair of coordinates of the calculated target
-	return (2*pivotRow - redRow),
-		(2*pivotCol - redCol)
+	// Release any ghosts that have now waited long enough
+	gs.releaseGhosts()
 }
 
 /*
-Returns the chase location of the orange ghost
-(i.e. Pacman's exact location, the same as red's target most of the time)
-Though, if close enough to Pacman, it should choose its scatter target
+Advance the release timer by a step, releasing the next ghost if Pacman goes
+too long without eating a pellet
 */
-func (gs *gameState) getChaseTargetOrange() (int8, int8) {
-
-	// Get Pacman's current location
-	pacmanRow, pacmanCol := gs.pacmanLoc.getCoords()
-
-	// Get the orange ghost's current location
-	orangeRow, orangeCol := gs.ghosts[orange].loc.getCoords()
+func (gs *gameState) stepReleaseTimer() {
 
-	// If Pacman is far enough from the ghost, return Pacman's location
-	if gs.distSq(orangeRow, orangeCol, pacmanRow, pacmanCol) >= 64 {
-		return (pacmanRow),
-			(pacmanCol)
+	// If there's no release timer, there's nothing to do
+	if !gs.rules.PelletRelease || gs.levelRules.ReleaseSteps == 0 {
+		return
 	}
 
-	// Otherwise, return the scatter location of orange
-	return gs.ghosts[orange].scatterTarget.getCoords()
+	// Once the timer runs out, release the next ghost and start it over
+	gs.releaseSteps++
+	if gs.releaseSteps >= gs.levelRules.ReleaseSteps {
+		gs.releaseSteps = 0
+		if ghost := gs.nextHeldGhost(); ghost != nil {
+			ghost.setHeld(false)
+		}
+	}
 }
 
-// Returns the chase location of an arbitrary ghost color
-func (gs *gameState) getChaseTarget(color uint8) (int8, int8) {
-	switch color {
-	case red:
-		return gs.getChaseTargetRed()
-	case pink:
-		return gs.getChaseTargetPink()
-	case cyan:
-		return gs.getChaseTargetCyan()
-	case orange:
-		return gs.getChaseTargetOrange()
+// Start the ghosts' pellet counters over, and stop using the global counter
+func (gs *gameState) resetReleaseCounters() {
+	for _, ghost := range gs.ghosts {
+		ghost.setPelletCounter(0)
 	}
-	return emptyLoc.getCoords()
+	gs.globalCounterActive = false
+	gs.globalPelletCounter = 0
 }
@@ -0,0 +1,38 @@
+package game
+
+import (
+	"testing"
+)
+
+/*
+Check that the Pacman move period limits manual moves, but not the moves
+along a tracking update's path
+*/
+func TestPacmanMovePeriod(t *testing.T) {
+
+	// Limit Pacman's manual moves to one every 11 ticks
+	configTestRules(t, func(rules *Rules) {
+		rules.Levels = []LevelRules{defaultLevelRules(12)}
+		rules.Levels[0].PacmanMovePeriod = 11
+	})
+	sim := NewSimulator(1)
+	sim.Step([]byte("P"))
+
+	// Only the first of two manual moves in a row should count
+	startRow, startCol := sim.state.pacmanLoc.getCoords()
+	sim.Step([]byte("a"), []byte("a"))
+	if row, col := sim.state.pacmanLoc.getCoords(); row != startRow ||
+		col != startCol-1 {
+		t.Fatalf("Pacman at (%d, %d) after two quick moves, expected (%d, %d)",
+			row, col, startRow, startCol-1)
+	}
+
+	// A tracking update a few spaces away should move Pacman all the way
+	targetCol := startCol - 4
+	sim.Step([]byte{'x', byte(startRow), byte(targetCol)})
+	if row, col := sim.state.pacmanLoc.getCoords(); row != startRow ||
+		col != targetCol {
+		t.Fatalf("Pacman at (%d, %d) after a tracking update, expected (%d, %d)",
+			row, col, startRow, targetCol)
+	}
+}
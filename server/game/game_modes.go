@@ -21,12 +21,6 @@ var modeNames [numModes]string = [...]string{
 
 // Helper function to get the game mode
 func (gs *gameState) getMode() uint8 {
-
-	// (Read) lock the game mode
-	gs.muMode.RLock()
-	defer gs.muMode.RUnlock()
-
-	// Return the current game mode
 	return gs.mode
 }
 
@@ -42,12 +36,7 @@ func (gs *gameState) setMode(mode uint8) {
 			modeNames[currMode], modeNames[mode], gs.getCurrTicks())
 	}
 
-	// (Write) lock the game mode
-	gs.muMode.Lock()
-	{
-		gs.mode = mode // Update the game mode
-	}
-	gs.muMode.Unlock()
+	gs.mode = mode // Update the game mode
 }
 
 /***************************** Last Unpaused Mode *****************************/
@@ -55,10 +44,6 @@ func (gs *gameState) setMode(mode uint8) {
 // Helper function to get the last unpaused mode
 func (gs *gameState) getLastUnpausedMode() uint8 {
 
-	// (Read) lock the game mode
-	gs.muMode.RLock()
-	defer gs.muMode.RUnlock()
-
 	// If the current mode is not paused, return it
 	if gs.mode != paused {
 		return gs.mode
@@ -81,12 +66,33 @@ func (gs *gameState) setLastUnpausedMode(mode uint8) {
 			modeNames[unpausedMode], modeNames[mode], gs.getCurrTicks())
 	}
 
-	// (Write) lock the game mode
-	gs.muMode.Lock()
-	{
-		gs.lastUnpausedMode = mode // Update the game mode
+	gs.lastUnpausedMode = mode // Update the game mode
+}
+
+/******************************** Mode Schedule *******************************/
+
+// Helper function to get the current wave of the mode schedule
+func (gs *gameState) getWave() uint8 {
+	return gs.waveIdx
+}
+
+/*
+Helper function to start a wave of the mode schedule, changing the mode
+(or the last unpaused mode, if paused) and the mode steps to match it
+*/
+func (gs *gameState) startWave(waveIdx uint8) {
+
+	gs.waveIdx = waveIdx // Update the wave
+
+	// Change the mode to that of the wave
+	if gs.isPaused() {
+		gs.setLastUnpausedMode(waveMode(waveIdx))
+	} else {
+		gs.setMode(waveMode(waveIdx))
 	}
-	gs.muMode.Unlock()
+
+	// Set the mode steps to the length of the wave
+	gs.setModeSteps(gs.levelRules.waveDuration(waveIdx))
 }
 
 /******************************** Pause / Play ********************************/
@@ -119,7 +125,7 @@ func (gs *gameState) pause() {
 func (gs *gameState) play() {
 
 	// If the game engine is already playing or can't play, return
-	if !gs.isPaused() || gs.getLives() == 0 || gs.getCurrTicks() == 0xffff {
+	if !gs.isPaused() || gs.getLives() == 0 || gs.getCurrTicks() == maxTicks {
 		return
 	}
 
@@ -133,61 +139,58 @@ func (gs *gameState) play() {
 
 /*************************** Pausing on Next Update ***************************/
 
-// Helper function to return whether the game should pause after next update
-func (gs *gameState) getPauseOnUpdate() bool {
+/*
+Helper function to advance a paused game by a single update (ghost update,
+collisions, step events and ghost plans), pausing again afterwards
+*/
+func (gs *gameState) stepUpdate() {
+
+	// If the game engine is already playing, there's no step to take
+	if !gs.isPaused() {
+		return
+	}
+
+	// Play the game, but set it to be paused at the next update
+	pauseOnUpdate := gs.getPauseOnUpdate()
+	gs.setPauseOnUpdate(true)
+	gs.play()
 
-	// (Read) lock the game mode
-	gs.muMode.RLock()
-	defer gs.muMode.RUnlock()
+	// If the game can't play, put the flag back the way it was
+	if gs.isPaused() {
+		gs.setPauseOnUpdate(pauseOnUpdate)
+		return
+	}
 
-	// Return whether the pause on update flag
+	// Log message to alert the user
+	log.Printf("\033[32m\033[2mGAME: Stepping to the next update "+
+		"(t = %d)\033[0m\n", gs.getCurrTicks())
+}
+
+// Helper function to return whether the game should pause after next update
+func (gs *gameState) getPauseOnUpdate() bool {
 	return gs.pauseOnUpdate
 }
 
 // Helper function to pause the game after the next update
 func (gs *gameState) setPauseOnUpdate(flag bool) {
-
-	// (Write) lock the game mode
-	gs.muMode.Lock()
-	{
-		gs.pauseOnUpdate = flag // Set a flag to pause at the next update
-	}
-	gs.muMode.Unlock()
+	gs.pauseOnUpdate = flag // Set a flag to pause at the next update
 }
 
 /********************************* Mode Steps *********************************/
 
 // Helper function to get the number of steps until the mode changes
 func (gs *gameState) getModeSteps() uint8 {
-
-	// (Read) lock the mode steps
-	gs.muModeSteps.RLock()
-	defer gs.muModeSteps.RUnlock()
-
-	// Return the mode steps
 	return gs.modeSteps
 }
 
 // Helper function to set the number of steps until the mode changes
 func (gs *gameState) setModeSteps(steps uint8) {
-
-	// (Write) lock the mode steps
-	gs.muModeSteps.Lock()
-	{
-		gs.modeSteps = steps // Set the mode steps
-	}
-	gs.muModeSteps.Unlock()
+	gs.modeSteps = steps // Set the mode steps
 }
 
 // Helper function to decrement the number of steps until the mode changes
 func (gs *gameState) decrementModeSteps() {
-
-	// (Write) lock the mode steps
-	gs.muModeSteps.Lock()
-	{
-		if gs.modeSteps != 0 {
-			gs.modeSteps-- // Decrease the mode steps
-		}
+	if gs.modeSteps != 0 {
+		gs.modeSteps-- // Decrease the mode steps
 	}
-	gs.muModeSteps.Unlock()
 }
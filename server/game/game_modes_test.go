@@ -0,0 +1,224 @@
+package game
+
+import (
+	"slices"
+	"testing"
+)
+
+/*
+Check that single-stepping a paused game (with 'n') moves ghosts faster than
+the game's steps (such as red with Cruise Elroy) the same way as playing it
+normally does
+*/
+func TestStepUpdateMovesFastGhosts(t *testing.T) {
+
+	// Speed red up well past the game's steps with Cruise Elroy
+	configTestRules(t, func(rules *Rules) {
+		rules.CruiseElroy = true
+		rules.ElroySpeedup = 4
+	})
+	sim := NewSimulator(3)
+	sim.state.elroyStage = 2
+	ghost := sim.state.ghosts[red]
+	if ghost.getMovePeriod() >= sim.state.getUpdatePeriod() {
+		t.Fatal("red should move faster than the game's steps")
+	}
+
+	// Play until red has left the ghost house, then pause
+	sim.Step([]byte("P"))
+	for ghost.isSpawning() || ghost.isTrapped() {
+		sim.Step()
+	}
+	sim.Step([]byte("p"))
+
+	// Single-step the game, while a copy of it plays normally
+	clone := sim.Clone()
+	sim.Step([]byte("n"))
+	clone.Step([]byte("P"))
+	for !sim.state.isPaused() {
+		sim.Step()
+		clone.Step()
+	}
+
+	// Red should have moved between the steps the same in both games
+	if sim.Ticks() != clone.Ticks() {
+		t.Fatalf("step ended at tick %d, play at tick %d", sim.Ticks(),
+			clone.Ticks())
+	}
+	stepped := sim.Ghost(red).Loc
+	played := clone.Ghost(red).Loc
+	if stepped != played {
+		t.Fatalf("red stepped to %+v, but played to %+v", stepped, played)
+	}
+}
+
+/*
+Check that with Cruise Elroy, only red speeds up (in two stages) as the
+pellets run low, that red keeps chasing in scatter mode once sped up, and that
+red starts the next level at its normal speed
+*/
+func TestCruiseElroy(t *testing.T) {
+	configTestRules(t, func(rules *Rules) {
+		rules.CruiseElroy = true
+		rules.ElroySpeedup = 2
+	})
+	gs := newGameState(1)
+	updatePeriod := gs.getUpdatePeriod()
+	wave := gs.getWave()
+
+	// Check the game's and each ghost's move period
+	checkPeriods := func(stage uint8, redPeriod uint8) {
+		t.Helper()
+		if gs.elroyStage != stage {
+			t.Fatalf("Cruise Elroy stage %d, expected %d", gs.elroyStage, stage)
+		}
+		if gs.getUpdatePeriod() != updatePeriod || gs.getWave() != wave {
+			t.Fatalf("stage %d: update period %d in wave %d, expected %d in "+
+				"wave %d", stage, gs.getUpdatePeriod(), gs.getWave(),
+				updatePeriod, wave)
+		}
+		for color, ghost := range gs.ghosts {
+			expected := updatePeriod
+			if color == int(red) {
+				expected = redPeriod
+			}
+			if period := ghost.getMovePeriod(); period != expected {
+				t.Fatalf("stage %d: %s ghost move period %d, expected %d",
+					stage, ghostNames[color], period, expected)
+			}
+		}
+	}
+	checkPeriods(0, updatePeriod)
+
+	// Collect pellets down to each anger threshold
+	for stage, threshold := range []uint16{
+		gs.rules.AngerThreshold1, gs.rules.AngerThreshold2,
+	} {
+		gs.numPellets = threshold + 1
+		modifyBit(&gs.pellets[1], int8(1), true)
+		gs.collectPellet(1, 1)
+		checkPeriods(uint8(stage+1), updatePeriod-2*uint8(stage+1))
+	}
+
+	// In scatter mode, red should plan the same move as if chasing
+	planRed := func(mode uint8) uint8 {
+		gs.setMode(mode)
+		ghost := gs.ghosts[red]
+		ghost.loc.updateCoords(5, 7)
+		ghost.loc.updateDir(left)
+		ghost.trappedSteps = 0
+		ghost.spawning = false
+		ghost.plan()
+		return ghost.nextLoc.getDir()
+	}
+	gs.pacmanLoc.updateCoords(23, 13)
+	scatterDir, chaseDir := planRed(scatter), planRed(chase)
+	if scatterDir != chaseDir {
+		t.Fatalf("red heads %s in scatter mode, but %s in chase mode",
+			dirNames[scatterDir], dirNames[chaseDir])
+	}
+
+	// Without Cruise Elroy, red would scatter elsewhere
+	gs.elroyStage = 0
+	if planRed(scatter) == planRed(chase) {
+		t.Fatal("red's scatter and chase moves should differ here")
+	}
+
+	// Clearing the level should bring red back to its normal speed
+	gs.elroyStage = 2
+	gs.levelReset()
+	checkPeriods(0, updatePeriod)
+}
+
+/*
+Play a number of steps of a game's mode schedule, returning the wave that
+each step was in
+*/
+func playWaves(gs *gameState, steps int) []uint8 {
+	waves := make([]uint8, steps)
+	for step := range waves {
+		gs.handleStepEvents()
+		waves[step] = gs.getWave()
+	}
+	return waves
+}
+
+// Check the lengths of the waves played, and the wave that they end in
+func checkWaves(t *testing.T, waves []uint8, lengths []uint8, final uint8) {
+	t.Helper()
+	var played []uint8
+	for step, wave := range waves {
+		if step == 0 || wave != waves[step-1] {
+			played = append(played, 0)
+		}
+		played[len(played)-1]++
+	}
+	played = played[:len(played)-1]
+	if !slices.Equal(played, lengths) || waves[len(waves)-1] != final {
+		t.Fatalf("played waves of %v steps, ending in wave %d, expected %v, "+
+			"ending in wave %d", played, waves[len(waves)-1], lengths, final)
+	}
+}
+
+/*
+Check that a schedule that doesn't repeat ends in a chase wave that lasts
+forever, and that the schedule starts over when Pacman dies (unless the
+ghosts are angry) or clears the level
+*/
+func TestModeSchedule(t *testing.T) {
+	configTestRules(t, func(rules *Rules) {
+		rules.Levels = []LevelRules{defaultLevelRules(12)}
+		rules.Levels[0].Waves = []uint8{2, 3, 4}
+		rules.Levels[0].RepeatWaves = false
+	})
+	gs := newGameState(1)
+
+	// The chase wave after the last wave should last forever
+	checkWaves(t, playWaves(gs, 300), []uint8{2, 3, 4}, 3)
+	if mode := gs.getLastUnpausedMode(); mode != chase ||
+		gs.getModeSteps() != 255 {
+		t.Fatalf("final wave in %s mode with %d steps left, expected chase "+
+			"mode with 255", modeNames[mode], gs.getModeSteps())
+	}
+
+	// Pacman dying should start the schedule over
+	gs.deathReset()
+	checkWaves(t, playWaves(gs, 4), []uint8{2}, 1)
+
+	// Clearing the level should start the schedule over
+	gs.levelReset()
+	checkWaves(t, playWaves(gs, 6), []uint8{2, 3}, 2)
+
+	// Once the ghosts are angry, Pacman dying shouldn't
+	gs.numPellets = gs.rules.AngerThreshold1
+	gs.deathReset()
+	if gs.getWave() != 2 {
+		t.Fatalf("wave %d after dying with angry ghosts, expected wave 2",
+			gs.getWave())
+	}
+}
+
+/*
+Check that from level 2 of the arcade profile, the last scatter wave is a
+single step, after a long chase wave, before chasing forever
+*/
+func TestArcadeModeSchedule(t *testing.T) {
+	rules, err := LoadRules("../../rules.json", "arcade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	configTestRules(t, func(r *Rules) { *r = *rules })
+	expected := []uint8{14, 40, 14, 40, 10, 255, 1}
+	if waves := rules.level(2).Waves; !slices.Equal(waves, expected) {
+		t.Fatalf("level 2 waves %v, expected %v", waves, expected)
+	}
+
+	// Clear level 1, then play through level 2's schedule
+	gs := newGameState(1)
+	gs.incrementLevel()
+	gs.levelReset()
+	checkWaves(t, playWaves(gs, 1000), expected, 7)
+	if mode := gs.getLastUnpausedMode(); mode != chase {
+		t.Fatalf("final wave in %s mode, expected chase", modeNames[mode])
+	}
+}
@@ -2,163 +2,262 @@ package game
 
 import (
 	"log"
-	"math/rand"
-	"sync"
 	"time"
 )
 
 /*
-	NOTE: at 24 ticks/sec, currTicks will experience an integer overflow after
-	about 45 minutes, so don't run it continuously for too long (indefinite
-	pausing is fine though, as it doesn't increment the current tick amount)
+	NOTE: at 24 ticks/sec, currTicks will only experience an integer overflow
+	after about 5 years, so the game stops at the maximum tick (maxTicks)
+	instead of wrapping around
 */
 
+/*
+The seed that new games use for random number generation (if nil, a new seed
+is picked from the clock for every game, so that a seed of zero can be used)
+*/
+var randomSeed *int64 = nil
+
+/*
+Configure the seed that new games use for random number generation, or nil to
+pick a new seed from the clock for every game (should be done before any games
+start)
+*/
+func ConfigRandomSeed(_randomSeed *int64) {
+	if _randomSeed == nil {
+		randomSeed = nil
+		return
+	}
+	seed := *_randomSeed
+	randomSeed = &seed
+}
+
+// Get the seed that a new game should use, if none is otherwise specified
+func getDefaultSeed() int64 {
+
+	// If a seed was configured, use it
+	if randomSeed != nil {
+		return *randomSeed
+	}
+
+	// Otherwise, pick a seed from the clock
+	return time.Now().UnixNano()
+}
+
 /*
 A game state object, to hold the internal game state and provide
 helper methods that can be accessed by the game engine
+
+Note: a game state has a single owner (the game engine or simulator that
+created it), and should only be read or modified by that owner's go-routine -
+other go-routines only ever see the serialized frames it produces, which are
+immutable copies taken once per loop iteration
 */
 type gameState struct {
 
-	/* Message header - 4 bytes */
-
-	currTicks uint16       // Current ticks (see note above)
-	muTicks   sync.RWMutex // Associated mutex
-
-	updatePeriod uint8        // Ticks / update
-	muPeriod     sync.RWMutex // Associated mutex
+	/* Message header - 6 bytes */
 
-	lastUnpausedMode uint8        // Last unpaused mode (for pausing purposes)
-	mode             uint8        // Game mode
-	muMode           sync.RWMutex // Associated mutex
-	pauseOnUpdate    bool         // Should pause when an update is ready
+	currTicks        uint32 // Current ticks (see note above)
+	updatePeriod     uint8  // Ticks / update
+	lastUnpausedMode uint8  // Last unpaused mode (for pausing purposes)
+	mode             uint8  // Game mode
+	pauseOnUpdate    bool   // Should pause when an update is ready
+	resetPending     bool   // Board reset (death or level) awaits an update
 
 	// The number of steps (update periods) before the mode changes
-	modeSteps   uint8
-	muModeSteps sync.RWMutex // Associated mutex
+	modeSteps uint8
 
-	// The number of steps (update periods) before a speedup penalty starts
-	levelSteps   uint16
-	muLevelSteps sync.RWMutex // Associated mutex
-
-	/* Game information - 4 bytes */
+	// The current wave of the level's mode schedule (see LevelRules.Waves)
+	waveIdx uint8
 
-	currScore uint16       // Current score
-	muScore   sync.RWMutex // Associated mutex
+	// The number of steps (update periods) before a speedup penalty starts
+	levelSteps uint16
 
-	currLevel uint8        // Current level (by default, starts at 1)
-	muLevel   sync.RWMutex // Associated mutex
+	/* Game information - 6 bytes */
 
-	currLives uint8        // Current lives (by default, starts at 3)
-	muLives   sync.RWMutex // Associated mutex
+	currScore uint32 // Current score
+	currLevel uint8  // Current level (by default, starts at 1)
+	currLives uint8  // Current lives (by default, starts at 3)
 
 	/* Pacman location - 2 bytes */
 
 	pacmanLoc *locationState
 
-	// A mutex for synchronizing updates to Pacman
-	muPacman sync.Mutex
-
 	/* Fruit location - 2 bytes */
 
 	fruitLoc *locationState
 
 	// The number of steps (update periods) before fruit disappears
 	fruitSteps uint8
-	muFruit    sync.RWMutex // Associated mutex
 
 	/* Ghosts - 4 * 3 = 12 bytes */
 
 	ghosts []*ghostState
 
-	// A mutex for synchronizing simultaneous updates of all ghosts
-	muGhosts sync.Mutex
-
-	// A wait group for synchronizing updates of multiple ghosts
-	wgGhosts *sync.WaitGroup
-
 	// A variable to keep track of the current ghost combo
 	ghostCombo uint8
 
+	/* Ghost release (see Rules.PelletRelease) - 3 bytes + 4 ghost counters */
+
+	globalPelletCounter uint8 // Pellets counted since Pacman died
+	globalCounterActive bool  // Whether the global pellet counter is in use
+	releaseSteps        uint8 // Steps since Pacman last ate a pellet
+
+	/* Cruise Elroy (see Rules.CruiseElroy) - 1 byte */
+
+	elroyStage uint8 // The stage of red's speed-up (0 for none)
+
 	/* Pellet State - 31 * 4 = 124 bytes */
 
 	// Pellets encoded within an array, with each uint32 acting as a bit array
 	pellets    [mazeRows]uint32
-	numPellets uint16       // Number of pellets
-	muPellets  sync.RWMutex // Associated mutex
+	numPellets uint16 // Number of pellets
 
 	/* Auxiliary (non-serialized) state information */
 
-	// Wall state
-	walls [mazeRows]uint32
+	// The maze that the game is played on (shared, never modified)
+	maze *mazeLayout
+
+	// The rules that the game is played with (shared, never modified)
+	rules *Rules
+
+	// The rules for the current level (shared, never modified)
+	levelRules *LevelRules
+
+	// The tick before which Pacman can't move again (see PacmanMovePeriod)
+	pacmanMoveTick uint32
 
-	// A random number generator for making frightened ghost decisions
-	rng *rand.Rand
+	// The score at which Pacman earns the next bonus life (0 for none)
+	nextBonusScore uint32
+
+	// The game events since the last frame, waiting to be sent to clients
+	events []GameEvent
+
+	// The seed for the random number generators of the ghosts
+	seed int64
 }
 
-// Create a new game state with default values
-func newGameState() *gameState {
+// Create a new game state with default values, given a random seed
+func newGameState(seed int64) *gameState {
 
 	// New game state object
 	gs := gameState{
 
 		// Message header
 		currTicks:    0,
-		updatePeriod: initUpdatePeriod,
+		updatePeriod: currRules.level(initLevel).UpdatePeriod,
 		mode:         paused,
 
 		// Additional header-related info
-		lastUnpausedMode: initMode,
+		lastUnpausedMode: waveMode(0),
 		pauseOnUpdate:    false,
-		modeSteps:        modeDurations[initMode],
-		levelSteps:       levelDuration,
+		modeSteps:        currRules.level(initLevel).waveDuration(0),
+		waveIdx:          0,
+		levelSteps:       currRules.LevelDuration,
 
 		// Game info
 		currScore: 0,
 		currLevel: initLevel,
-		currLives: initLives,
+		currLives: currRules.InitLives,
+
+		// Bonus lives
+		nextBonusScore: currRules.BonusLifeScore,
 
 		// Fruit
 		fruitSteps: 0,
 
 		// Ghosts
 		ghosts:     make([]*ghostState, numColors),
-		wgGhosts:   &sync.WaitGroup{},
 		ghostCombo: 0,
 
-		// RNG (random number generation) source
-		rng: rand.New(rand.NewSource(time.Now().UnixNano())),
+		// RNG (random number generation) seed
+		seed: seed,
 
-		// Pellet count at the start
-		numPellets: initPelletCount,
+		// Maze, with its pellet count at the start
+		maze:       currMaze,
+		numPellets: currMaze.numPellets,
+
+		// Rules of the game (and for the first level)
+		rules:      currRules,
+		levelRules: currRules.level(initLevel),
 	}
 
 	// Declare the initial locations of Pacman and the fruit
-	gs.pacmanLoc = newLocationStateCopy(pacmanSpawnLoc)
-	gs.fruitLoc = newLocationStateCopy(fruitSpawnLoc)
+	gs.pacmanLoc = newLocationStateCopy(gs.maze.pacmanSpawn)
+	gs.fruitLoc = newLocationStateCopy(gs.maze.fruitSpawn)
 
 	// Initialize the ghosts
 	for color := uint8(0); color < numColors; color++ {
 		gs.ghosts[color] = newGhostState(&gs, color)
 	}
 
-	// Copy over maze bit arrays
-	copy(gs.pellets[:], initPellets[:])
-	copy(gs.walls[:], initWalls[:])
+	// Copy over the pellet bit array
+	copy(gs.pellets[:], gs.maze.pellets[:])
+
+	// Release any ghosts that don't need to wait for pellets
+	gs.releaseGhosts()
 
 	// Return the new game state
 	return &gs
 }
 
-/**************************** Curr Ticks Functions ****************************/
+// Create a new game state in response to a reset command
+func newResetGameState(seed int64) *gameState {
 
-// Helper function to get the current ticks
-func (gs *gameState) getCurrTicks() uint16 {
+	// New game state object, with the seed logged so it can be reproduced
+	gs := newGameState(seed)
+	gs.logSeed()
+
+	// Bring the ghosts into the maze and plan their first moves
+	gs.updateAllGhosts()
+	gs.handleStepEvents()
+	gs.planAllGhosts()
+
+	// Return the new game state
+	return gs
+}
+
+/*
+Create a deep copy of a game state, which can be advanced independently of
+the original (for example, to search ahead without affecting the real game)
+*/
+func (gs *gameState) clone() *gameState {
+
+	// Copy over all the values (including the pellet bit array)
+	gc := *gs
+
+	// Copy the locations of Pacman and the fruit
+	gc.pacmanLoc = newLocationStateCopy(gs.pacmanLoc)
+	gc.fruitLoc = newLocationStateCopy(gs.fruitLoc)
+
+	// Copy the ghosts, tying them to the new game state
+	gc.ghosts = make([]*ghostState, len(gs.ghosts))
+	for color, ghost := range gs.ghosts {
+		gc.ghosts[color] = ghost.clone(&gc)
+	}
+
+	// The copy has its own events, starting from none
+	gc.events = nil
+
+	// Return the copied game state
+	return &gc
+}
 
-	// (Read) lock the current ticks
-	gs.muTicks.RLock()
-	defer gs.muTicks.RUnlock()
+/****************************** RNG Seed Functions ****************************/
 
-	// Return the current ticks
+// Get the seed used for random number generation in this game
+func (gs *gameState) getSeed() int64 {
+	return gs.seed
+}
+
+// Send the seed used for random number generation to the terminal
+func (gs *gameState) logSeed() {
+	log.Printf("\033[36mGAME: Random seed = %d\033[0m\n", gs.getSeed())
+}
+
+/**************************** Curr Ticks Functions ****************************/
+
+// Helper function to get the current ticks
+func (gs *gameState) getCurrTicks() uint32 {
 	return gs.currTicks
 }
 
@@ -169,31 +268,20 @@ func (gs *gameState) nextTick() {
 	currTicks := gs.getCurrTicks()
 
 	// If the current ticks are at the maximum, return
-	if currTicks == 0xffff {
+	if currTicks == maxTicks {
 		return
-	} else if currTicks == 0xfffe {
+	} else if currTicks == maxTicks-1 {
 		gs.pause()
 		log.Println("\033[31mGAME: Max tick limit reached\033[0m")
 	}
 
-	// (Write) lock the current ticks
-	gs.muTicks.Lock()
-	{
-		gs.currTicks++ // Update the current ticks
-	}
-	gs.muTicks.Unlock()
+	gs.currTicks++ // Update the current ticks
 }
 
 /**************************** Upd Period Functions ****************************/
 
 // Helper function to get the update period
 func (gs *gameState) getUpdatePeriod() uint8 {
-
-	// (Read) lock the update period
-	gs.muPeriod.RLock()
-	defer gs.muPeriod.RUnlock()
-
-	// Return the update period
 	return gs.updatePeriod
 }
 
@@ -204,12 +292,7 @@ func (gs *gameState) setUpdatePeriod(period uint8) {
 	log.Printf("\033[36mGAME: Update period changed (%d -> %d) (t = %d)\033[0m\n",
 		gs.getUpdatePeriod(), period, gs.getCurrTicks())
 
-	// (Write) lock the update period
-	gs.muPeriod.Lock()
-	{
-		gs.updatePeriod = period // Update the update period
-	}
-	gs.muPeriod.Unlock()
+	gs.updatePeriod = period // Update the update period
 }
 
 /******************************* Mode Functions *******************************/
@@ -219,41 +302,53 @@ func (gs *gameState) setUpdatePeriod(period uint8) {
 /**************************** Game Score Functions ****************************/
 
 // Helper function to get the current score of the game
-func (gs *gameState) getScore() uint16 {
-
-	// (Read) lock the current score
-	gs.muScore.RLock()
-	defer gs.muScore.RUnlock()
-
-	// Return the current score
+func (gs *gameState) getScore() uint32 {
 	return gs.currScore
 }
 
 // (For performance) helper function to increment the current score of the game
 func (gs *gameState) incrementScore(change uint16) {
 
-	// Calculate the next score, capping at the maximum 16-bit unsigned int
-	score := uint32(gs.currScore)
-	score = min(score+uint32(change), 65535)
+	// Calculate the next score, capping at the maximum 32-bit unsigned int
+	score := uint64(gs.getScore())
+	score = min(score+uint64(change), 0xffffffff)
 
-	// (Write) lock the current score
-	gs.muScore.Lock()
-	{
-		gs.currScore = uint16(score) // Update the current score
+	gs.currScore = uint32(score) // Update the current score
+
+	// Award a bonus life for each bonus score that the new score reached
+	for gs.nextBonusScore != 0 && gs.currScore >= gs.nextBonusScore {
+		gs.awardBonusLife()
+	}
+}
+
+// Helper function to award a bonus life, then move to the next bonus score
+func (gs *gameState) awardBonusLife() {
+
+	// Award the life, unless Pacman already has the maximum number of lives
+	lives := gs.getLives()
+	if lives < gs.rules.MaxLives {
+
+		// Send a message to the terminal
+		log.Printf("\033[32mGAME: Bonus life at %d points (%d -> %d) (t = %d)\033[0m\n",
+			gs.nextBonusScore, lives, lives+1, gs.getCurrTicks())
+
+		gs.currLives = lives + 1
+		gs.emitEvent(EventBonusLife)
+	}
+
+	// Move on to the next bonus score, if there is one (and it fits)
+	next := uint64(gs.nextBonusScore) + uint64(gs.rules.BonusLifeInterval)
+	if gs.rules.BonusLifeInterval == 0 || next > 0xffffffff {
+		gs.nextBonusScore = 0
+	} else {
+		gs.nextBonusScore = uint32(next)
 	}
-	gs.muScore.Unlock()
 }
 
 /**************************** Game Level Functions ****************************/
 
 // Helper function to get the current level of the game
 func (gs *gameState) getLevel() uint8 {
-
-	// (Read) lock the current level
-	gs.muLevel.RLock()
-	defer gs.muLevel.RUnlock()
-
-	// Return the current level
 	return gs.currLevel
 }
 
@@ -264,16 +359,11 @@ func (gs *gameState) setLevel(level uint8) {
 	log.Printf("\033[32mGAME: Level changed (%d -> %d) (t = %d)\033[0m\n",
 		gs.getLevel(), level, gs.getCurrTicks())
 
-	// (Write) lock the current level
-	gs.muLevel.Lock()
-	{
-		gs.currLevel = level // Update the level
+	gs.currLevel = level // Update the level
 
-		// Adjust the initial update period accordingly
-		suggestedPeriod := int(initUpdatePeriod) - 2*(int(level)-1)
-		gs.setUpdatePeriod(uint8(max(1, suggestedPeriod)))
-	}
-	gs.muLevel.Unlock()
+	// Look up the rules for this level, and adjust the update period
+	gs.levelRules = gs.rules.level(level)
+	gs.setUpdatePeriod(gs.levelRules.UpdatePeriod)
 }
 
 // Helper function to increment the game level
@@ -291,28 +381,16 @@ func (gs *gameState) incrementLevel() {
 	log.Printf("\033[32mGAME: Next level (%d -> %d) (t = %d)\033[0m\n",
 		level, level+1, gs.getCurrTicks())
 
-	// (Write) lock the current level
-	gs.muLevel.Lock()
-	{
-		gs.currLevel++ // Update the level
+	gs.currLevel++ // Update the level
 
-		// Adjust the initial update period accordingly
-		suggestedPeriod := int(initUpdatePeriod) - 2*int(level)
-		gs.setUpdatePeriod(uint8(max(1, suggestedPeriod)))
-	}
-	gs.muLevel.Unlock()
+	// Look up the rules for this level (applied when the level resets)
+	gs.levelRules = gs.rules.level(level + 1)
 }
 
 /**************************** Game Lives Functions ****************************/
 
 // Helper function to get the lives left
 func (gs *gameState) getLives() uint8 {
-
-	// (Read) lock the current lives
-	gs.muLives.RLock()
-	defer gs.muLives.RUnlock()
-
-	// Return the current lives
 	return gs.currLives
 }
 
@@ -323,12 +401,7 @@ func (gs *gameState) setLives(lives uint8) {
 	log.Printf("\033[36mGAME: Lives changed (%d -> %d)\033[0m\n",
 		gs.getLives(), lives)
 
-	// (Write) lock the current lives
-	gs.muLives.Lock()
-	{
-		gs.currLives = lives // Update the lives
-	}
-	gs.muLives.Unlock()
+	gs.currLives = lives // Update the lives
 }
 
 // Helper function to decrement the lives left
@@ -346,65 +419,37 @@ func (gs *gameState) decrementLives() {
 	log.Printf("\033[31mGAME: Pacman lost a life (%d -> %d) (t = %d)\033[0m\n",
 		lives, lives-1, gs.getCurrTicks())
 
-	// (Write) lock the current lives
-	gs.muLives.Lock()
-	{
-		gs.currLives-- // Update the lives
-	}
-	gs.muLives.Unlock()
+	gs.currLives-- // Update the lives
 }
 
 /****************************** Pellet Functions ******************************/
 
 // Helper function to get the number of pellets
 func (gs *gameState) getNumPellets() uint16 {
-
-	// (Read) lock the number of pellets
-	gs.muPellets.RLock()
-	defer gs.muPellets.RUnlock()
-
-	// Return the number of pellets
 	return gs.numPellets
 }
 
 // Helper function to decrement the number of pellets
 func (gs *gameState) decrementNumPellets() {
-
-	// (Write) lock the number of pellets
-	gs.muPellets.Lock()
-	{
-		if gs.numPellets != 0 {
-			gs.numPellets--
-		}
+	if gs.numPellets != 0 {
+		gs.numPellets--
 	}
-	gs.muPellets.Unlock()
 }
 
 // Reset all the pellets on the board
 func (gs *gameState) resetPellets() {
 
-	// (Write) lock the pellets array and number of pellets
-	gs.muPellets.Lock()
-	{
-		// Copy over pellet bit array
-		copy(gs.pellets[:], initPellets[:])
+	// Copy over pellet bit array
+	copy(gs.pellets[:], gs.maze.pellets[:])
 
-		// Set the number of pellets to be the default
-		gs.numPellets = initPelletCount
-	}
-	gs.muPellets.Unlock()
+	// Set the number of pellets to be the default
+	gs.numPellets = gs.maze.numPellets
 }
 
 /************************** Fruit Spawning Functions **************************/
 
 // Helper function to get the number of steps until the fruit disappears
 func (gs *gameState) getFruitSteps() uint8 {
-
-	// (Read) lock the fruit steps
-	gs.muFruit.RLock()
-	defer gs.muFruit.RUnlock()
-
-	// Return the fruit steps
 	return gs.fruitSteps
 }
 
@@ -417,63 +462,58 @@ func (gs *gameState) fruitExists() bool {
 
 // Helper function to set the number of steps until the fruit disappears
 func (gs *gameState) setFruitSteps(steps uint8) {
+	gs.fruitSteps = steps // Set the fruit steps
+}
 
-	// (Write) lock the fruit steps
-	gs.muFruit.Lock()
-	{
-		gs.fruitSteps = steps // Set the fruit steps
+// Helper function to get the rules for this level's type of fruit
+func (gs *gameState) getFruit() *FruitRules {
+	return gs.rules.fruit(gs.levelRules)
+}
+
+// Helper function to spawn the fruit (of this level's type)
+func (gs *gameState) spawnFruit() {
+
+	// Keep track of this level's type of fruit
+	fruit := gs.getFruit()
+
+	// Send a message to the terminal
+	log.Printf("\033[32mGAME: Fruit spawned (%s, %d points) (t = %d)\033[0m\n",
+		fruit.Name, fruit.Points, gs.getCurrTicks())
+
+	// Move the fruit to its spawn location (or the maze's, if it has none)
+	if fruit.Spawn != nil {
+		gs.fruitLoc.updateCoords(fruit.Spawn[0], fruit.Spawn[1])
+	} else {
+		gs.fruitLoc.updateCoords(gs.maze.fruitSpawn.getCoords())
 	}
-	gs.muFruit.Unlock()
+
+	gs.setFruitSteps(fruit.Duration) // Set the fruit steps
 }
 
 // Helper function to decrement the number of fruit steps
 func (gs *gameState) decrementFruitSteps() {
-
-	// (Write) lock the fruit steps
-	gs.muFruit.Lock()
-	{
-		if gs.fruitSteps != 0 {
-			gs.fruitSteps-- // Decrease the fruit steps
-		}
+	if gs.fruitSteps != 0 {
+		gs.fruitSteps-- // Decrease the fruit steps
 	}
-	gs.muFruit.Unlock()
 }
 
 /***************************** Level Steps Passed *****************************/
 
 // Helper function to get the number of steps until the level speeds up
 func (gs *gameState) getLevelSteps() uint16 {
-
-	// (Read) lock the level steps
-	gs.muLevelSteps.RLock()
-	defer gs.muLevelSteps.RUnlock()
-
-	// Return the level steps
 	return gs.levelSteps
 }
 
 // Helper function to set the number of steps until the level speeds up
 func (gs *gameState) setLevelSteps(steps uint16) {
-
-	// (Write) lock the level steps
-	gs.muLevelSteps.Lock()
-	{
-		gs.levelSteps = steps // Set the level steps
-	}
-	gs.muLevelSteps.Unlock()
+	gs.levelSteps = steps // Set the level steps
 }
 
 // Helper function to decrement the number of steps until the mode changes
 func (gs *gameState) decrementLevelSteps() {
-
-	// (Write) lock the level steps
-	gs.muLevelSteps.Lock()
-	{
-		if gs.levelSteps != 0 {
-			gs.levelSteps-- // Decrease the level steps
-		}
+	if gs.levelSteps != 0 {
+		gs.levelSteps-- // Decrease the level steps
 	}
-	gs.muLevelSteps.Unlock()
 }
 
 /***************************** Step-Related Events ****************************/
@@ -487,29 +527,9 @@ func (gs *gameState) handleStepEvents() {
 	// Get the current level steps
 	levelSteps := gs.getLevelSteps()
 
-	// If the mode steps are 0, change the mode
+	// If the mode steps are 0, move on to the next wave (changing the mode)
 	if modeSteps == 0 {
-		switch gs.getMode() {
-		// chase -> scatter
-		case chase:
-			gs.setMode(scatter)
-			gs.setModeSteps(modeDurations[scatter])
-		// scatter -> chase
-		case scatter:
-			gs.setMode(chase)
-			gs.setModeSteps(modeDurations[chase])
-		case paused:
-			switch gs.getLastUnpausedMode() {
-			// chase -> scatter
-			case chase:
-				gs.setLastUnpausedMode(scatter)
-				gs.setModeSteps(modeDurations[scatter])
-			// scatter -> chase
-			case scatter:
-				gs.setLastUnpausedMode(chase)
-				gs.setModeSteps(modeDurations[chase])
-			}
-		}
+		gs.startWave(gs.levelRules.nextWave(gs.getWave()))
 
 		// Reverse the directions of all ghosts to indicate a mode switch
 		gs.reverseAllGhosts()
@@ -525,11 +545,15 @@ func (gs *gameState) handleStepEvents() {
 		gs.setUpdatePeriod(uint8(max(1, int(gs.getUpdatePeriod())-2)))
 
 		// Reset the level steps to the level penalty duration
-		gs.setLevelSteps(levelPenaltyDuration)
+		gs.setLevelSteps(gs.rules.LevelPenaltyDuration)
 	}
 
-	// Decrement the mode steps
-	if gs.getNumPellets() >= angerThreshold1 {
+	/*
+		Decrement the mode steps (unless the ghosts are angry or the wave is
+		endless) - with Cruise Elroy, only red gets angry, so the schedule goes on
+	*/
+	if (gs.rules.CruiseElroy || gs.getNumPellets() >= gs.rules.AngerThreshold1) &&
+		!gs.levelRules.isFinalWave(gs.getWave()) {
 		gs.decrementModeSteps()
 	}
 
@@ -538,4 +562,7 @@ func (gs *gameState) handleStepEvents() {
 
 	// Decrement the fruit steps
 	gs.decrementFruitSteps()
+
+	// Release the next ghost if Pacman hasn't eaten a pellet in a while
+	gs.stepReleaseTimer()
 }
@@ -0,0 +1,201 @@
+package game
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+/*
+Check that a clone of a game plays out the same as the original when given
+the same commands, and can diverge from it without changing it
+*/
+func TestCloneDiverges(t *testing.T) {
+
+	// Play partway through a game
+	sim := NewSimulator(7)
+	sim.Step([]byte("P"))
+	for step := 0; step < 300; step++ {
+		sim.Step()
+	}
+	before := sim.Snapshot()
+
+	// Play the clone on with different moves (until Pacman has moved)
+	clone := sim.Clone()
+	for step := 0; step < 100; step++ {
+		clone.Step([]byte("a"))
+	}
+	if bytes.Equal(clone.Snapshot(), before) {
+		t.Fatal("the clone did not change after playing on")
+	}
+
+	// The original should be left exactly as it was
+	if !bytes.Equal(sim.Snapshot(), before) {
+		t.Fatal("playing the clone changed the original game")
+	}
+
+	// Given the same commands, another clone should match the original
+	twin := sim.Clone()
+	for step := 0; step < 100; step++ {
+		sim.Step([]byte("d"))
+		twin.Step([]byte("d"))
+		if !bytes.Equal(sim.Snapshot(), twin.Snapshot()) {
+			t.Fatalf("the clone diverged from the original at step %d", step)
+		}
+	}
+}
+
+/*
+Check that bonus lives are awarded at the bonus score, then at every interval
+after it (up to the maximum lives), each with a bonus life event
+*/
+func TestBonusLives(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(rules *Rules)
+		score   uint32   // the score to start from
+		changes []uint16 // the score changes, in order
+		lives   uint8    // the lives expected afterwards
+		next    uint32   // the next bonus score expected afterwards
+	}{
+		{"below threshold", func(r *Rules) { r.BonusLifeScore = 10000 },
+			0, []uint16{9990}, 3, 10000},
+		{"threshold only", func(r *Rules) { r.BonusLifeScore = 10000 },
+			0, []uint16{9990, 10, 50000}, 4, 0},
+		{"interval", func(r *Rules) {
+			r.BonusLifeScore, r.BonusLifeInterval = 10000, 5000
+		}, 0, []uint16{10000, 4999, 1}, 5, 20000},
+		{"max lives", func(r *Rules) {
+			r.BonusLifeScore, r.BonusLifeInterval, r.MaxLives = 100, 100, 4
+		}, 0, []uint16{100, 100, 100}, 4, 400},
+		{"several thresholds at once", func(r *Rules) {
+			r.BonusLifeScore, r.BonusLifeInterval, r.MaxLives = 1000, 1000, 9
+		}, 0, []uint16{3500}, 6, 4000},
+		{"next past the maximum score", func(r *Rules) {
+			r.BonusLifeScore, r.BonusLifeInterval = 0xfffff000, 0x1000
+		}, 0xffffef00, []uint16{0x1000, 0xffff}, 4, 0},
+		{"next at the maximum score", func(r *Rules) {
+			r.BonusLifeScore, r.BonusLifeInterval = 0xfffff000, 0xfff
+		}, 0xffffef00, []uint16{0x1000, 0xffff}, 5, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configTestRules(t, test.modify)
+			gs := newGameState(1)
+			gs.currScore = test.score
+			for _, change := range test.changes {
+				gs.incrementScore(change)
+			}
+			if gs.getLives() != test.lives || gs.nextBonusScore != test.next {
+				t.Fatalf("%d lives (next bonus at %d), expected %d (next at %d)",
+					gs.getLives(), gs.nextBonusScore, test.lives, test.next)
+			}
+
+			// Each bonus life should have its own event
+			awarded := int(test.lives - currRules.InitLives)
+			if len(gs.events) != awarded {
+				t.Fatalf("%d events for %d bonus lives", len(gs.events), awarded)
+			}
+			for idx, event := range gs.events {
+				lives := currRules.InitLives + uint8(idx) + 1
+				if event.Event != EventBonusLife || event.Lives != lives {
+					t.Fatalf("event %d is %+v, expected a bonus life to %d",
+						idx, event, lives)
+				}
+			}
+		})
+	}
+}
+
+/*
+Check that each level spawns its own type of fruit, at the fruit's spawn
+location (or the maze's, if it has none), and that the frame reports its type
+*/
+func TestFruitPerLevel(t *testing.T) {
+	prevLegacyProtocol := legacyProtocol
+	t.Cleanup(func() { ConfigLegacyProtocol(prevLegacyProtocol) })
+	ConfigLegacyProtocol(false)
+	configTestRules(t, func(rules *Rules) {
+		rules.Fruits = []FruitRules{
+			defaultFruitRules("cherry", 100),
+			defaultFruitRules("strawberry", 300),
+		}
+		rules.Fruits[1].Spawn = &[2]int8{11, 9}
+		rules.Levels = []LevelRules{defaultLevelRules(12), defaultLevelRules(12)}
+		rules.Levels[1].FruitType = 1
+	})
+	gs := newGameState(1)
+	spawnRow, spawnCol := gs.maze.fruitSpawn.getCoords()
+
+	// Each level, with the fruit it should spawn
+	levels := []struct {
+		fruitType uint8
+		row, col  int8
+		points    uint32
+	}{
+		{0, spawnRow, spawnCol, 100},
+		{1, 11, 9, 300},
+		{1, 11, 9, 300}, // levels past the table use its last entry
+	}
+	for idx, level := range levels {
+		if idx > 0 {
+			gs.incrementLevel()
+			gs.levelReset()
+		}
+
+		// The fruit should spawn where its rules say
+		gs.spawnFruit()
+		if row, col := gs.fruitLoc.getCoords(); row != level.row ||
+			col != level.col {
+			t.Fatalf("level %d: fruit at (%d, %d), expected (%d, %d)",
+				gs.getLevel(), row, col, level.row, level.col)
+		}
+
+		// The frame should report the type of fruit (after its duration)
+		outputBuf := make([]byte, serBufSize)
+		gs.serFull(outputBuf, 0)
+		if fruitType := outputBuf[39]; fruitType != level.fruitType {
+			t.Fatalf("level %d: fruit type %d, expected %d", gs.getLevel(),
+				fruitType, level.fruitType)
+		}
+
+		// Collecting it should be worth its points
+		score := gs.getScore()
+		gs.pacmanLoc.updateCoords(level.row, level.col)
+		gs.collectPellet(level.row, level.col)
+		if points := gs.getScore() - score; points != level.points {
+			t.Fatalf("level %d: fruit worth %d points, expected %d",
+				gs.getLevel(), points, level.points)
+		}
+	}
+}
+
+/*
+Check that a configured seed (even zero) is used for new games, and that with
+no seed configured, new games get seeds from the clock
+*/
+func TestConfigRandomSeed(t *testing.T) {
+
+	// Restore the seed after the test
+	prevRandomSeed := randomSeed
+	t.Cleanup(func() { randomSeed = prevRandomSeed })
+
+	// A configured seed should be copied, not shared with the caller
+	for _, seed := range []int64{0, -3, 42} {
+		configured := seed
+		ConfigRandomSeed(&configured)
+		configured++
+		if got := getDefaultSeed(); got != seed {
+			t.Fatalf("configured seed %d, got %d", seed, got)
+		}
+	}
+
+	// Without a seed, consecutive games should get different seeds
+	ConfigRandomSeed(nil)
+	first := getDefaultSeed()
+	time.Sleep(time.Millisecond)
+	if second := getDefaultSeed(); first == second {
+		t.Fatalf("unset seed gave %d twice", first)
+	}
+}
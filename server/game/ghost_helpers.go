@@ -9,18 +9,24 @@ import (
 // Respawn the ghost
 func (g *ghostState) reset() {
 
-	// Mark this operation as done once we return
-	defer g.game.wgGhosts.Done()
-
 	// If the ghost is inactive (in a game with fewer ghosts), skip
 	if g.color >= numActiveGhosts {
 		return
 	}
 
-	// Set the ghost to be trapped, spawning, and not frightened
+	/*
+		Set the ghost to be trapped (or held until the pellet counters release
+		it, if configured), spawning, and not frightened
+	*/
 	g.setSpawning(true)
-	g.setTrappedSteps(ghostTrappedSteps[g.color])
+	if g.game.rules.PelletRelease {
+		g.setTrappedSteps(0)
+		g.setHeld(true)
+	} else {
+		g.setTrappedSteps(ghostTrappedSteps[g.color])
+	}
 	g.setFrightSteps(0)
+	g.setEyes(false)
 
 	// Set the current ghost to be at an empty location
 	g.loc.copyFrom(emptyLoc)
@@ -29,7 +35,7 @@ func (g *ghostState) reset() {
 		Set the current location of the ghost to be its spawn point
 		(or pink's spawn location, in the case of red, so it spawns in the box)
 	*/
-	g.nextLoc.copyFrom(ghostSpawnLocs[g.color])
+	g.nextLoc.copyFrom(g.game.maze.ghostSpawns[g.color])
 }
 
 /****************************** Ghost Respawning ******************************/
@@ -37,14 +43,24 @@ func (g *ghostState) reset() {
 // Respawn the ghost
 func (g *ghostState) respawn() {
 
-	// Mark this operation as done once we return
-	defer g.game.wgGhosts.Done()
-
 	// If the ghost is inactive (in a game with fewer ghosts), skip
 	if g.color >= numActiveGhosts {
 		return
 	}
 
+	/*
+		If configured, turn the ghost into eyes where it is, to find its own
+		way back to the ghost house (no longer frightened or trapped)
+	*/
+	if g.game.rules.GhostEyes {
+		g.setEaten(true)
+		g.setEyes(true)
+		g.setSpawning(false)
+		g.setFrightSteps(0)
+		g.setTrappedSteps(0)
+		return
+	}
+
 	// Set the ghost to be eaten and spawning
 	g.setSpawning(true)
 	g.setEaten(true)
@@ -52,15 +68,8 @@ func (g *ghostState) respawn() {
 	// Set the current ghost to be at an empty location
 	g.loc.copyFrom(emptyLoc)
 
-	/*
-		Set the current location of the ghost to be its spawn point
-		(or pink's spawn location, in the case of red, so it spawns in the box)
-	*/
-	if g.color == red {
-		g.nextLoc.updateCoords(ghostSpawnLocs[pink].getCoords())
-	} else {
-		g.nextLoc.updateCoords(ghostSpawnLocs[g.color].getCoords())
-	}
+	// Set the current location of the ghost to be its respawn point
+	g.nextLoc.updateCoords(g.respawnLoc().getCoords())
 	g.nextLoc.updateDir(up)
 }
 
@@ -69,30 +78,33 @@ func (g *ghostState) respawn() {
 // Update the ghost's position
 func (g *ghostState) update() {
 
-	// Mark the plan as done once we return
-	defer g.game.wgGhosts.Done()
-
 	/*
 		If the ghost is at the red spawn point and not moving downwards,
 		we can mark it as done spawning
 	*/
-	if g.loc.collidesWith(ghostSpawnLocs[red]) && g.loc.getDir() != down {
+	redSpawn := g.game.maze.ghostSpawns[red]
+	if g.loc.collidesWith(redSpawn) && g.loc.getDir() != down {
 		g.setSpawning(false)
 	}
 
-	// Set the ghost to be no longer eaten, if applicable
-	if g.isEaten() {
+	// Set the ghost to be no longer eaten, if applicable (unless it is eyes)
+	if g.isEaten() && !g.isEyes() {
 		g.setEaten(false)
 		g.setFrightSteps(0)
 	}
 
-	// Decrement the ghost's frightened steps count if necessary
-	if g.isFrightened() {
-		g.decFrightSteps()
-	}
-
 	// Copy the next location into the current location
 	g.loc.copyFrom(g.nextLoc)
+
+	/*
+		If the ghost is eyes and has reached its respawn point, it comes back
+		to life, spawning (to leave the ghost house again)
+	*/
+	if g.isEyes() && g.loc.collidesWith(g.respawnLoc()) {
+		g.setEyes(false)
+		g.setEaten(false)
+		g.setSpawning(true)
+	}
 }
 
 /******************** Ghost Planning (after serialization) ********************/
@@ -100,9 +112,6 @@ func (g *ghostState) update() {
 // Plan the ghost's next move
 func (g *ghostState) plan() {
 
-	// Mark the plan as done once we return
-	defer g.game.wgGhosts.Done()
-
 	// If the location is empty (i.e. after a reset/respawn), don't plan
 	if g.loc.isEmpty() {
 		return
@@ -110,11 +119,14 @@ func (g *ghostState) plan() {
 
 	// Determine the next position based on the current direction
 	g.nextLoc.advanceFrom(g.loc)
+	g.nextLoc.updateCoords(g.game.wrapCoords(g.nextLoc.getCoords()))
 
 	// If the ghost is trapped, reverse the current direction and return
 	if g.isTrapped() {
 		g.nextLoc.updateDir(g.nextLoc.getReversedDir())
-		g.decTrappedSteps()
+		if g.getTrappedSteps() > 0 {
+			g.decTrappedSteps()
+		}
 		return
 	}
 
@@ -122,23 +134,56 @@ func (g *ghostState) plan() {
 	frightSteps := g.getFrightSteps()
 	spawning := g.isSpawning()
 
+	/*
+		If the ghost is eyes and will reach its respawn point, turn it upwards
+		(to leave the ghost house again, once it comes back to life) and return
+	*/
+	eyes := g.isEyes()
+	if eyes && g.nextLoc.collidesWith(g.respawnLoc()) {
+		g.nextLoc.updateDir(up)
+		return
+	}
+
+	/*
+		Eyes head for red's spawn location (just outside the ghost house exit),
+		then enter the ghost house from there
+	*/
+	redSpawn := g.game.maze.ghostSpawns[red]
+	entering := eyes && (g.nextLoc.collidesWith(redSpawn) ||
+		g.game.ghostHouseAt(g.nextLoc.getCoords()) ||
+		g.game.ghostHouseExitAt(g.nextLoc.getCoords()))
+
 	// Decide on a target for this ghost, depending on the game mode
 	var targetRow, targetCol int8
+	chasing := false
 
 	// Capture the last unpaused current game mode (could be the current mode)
 	mode := g.game.getLastUnpausedMode()
 
+	// With Cruise Elroy, red keeps chasing in scatter mode
+	if g.color == red && g.game.elroyStage > 0 {
+		mode = chase
+	}
+
 	/*
+		If the ghost is eyes, target the ghost house (red's spawn location,
+		then the ghost's respawn point once entering)
+
 		If the ghost is spawning in the ghost house, choose red's spawn
 		location as the target to encourage it to leave the ghost house
 
 		Otherwise: pick chase or scatter targets, depending on the mode
 	*/
-	if spawning && !g.loc.collidesWith(ghostSpawnLocs[red]) &&
-		!g.nextLoc.collidesWith(ghostSpawnLocs[red]) {
-		targetRow, targetCol = ghostSpawnLocs[red].getCoords()
-	} else if mode == chase { // Chase mode targets
-		targetRow, targetCol = g.game.getChaseTarget(g.color)
+	if entering {
+		targetRow, targetCol = g.respawnLoc().getCoords()
+	} else if eyes {
+		targetRow, targetCol = redSpawn.getCoords()
+	} else if spawning && !g.loc.collidesWith(redSpawn) &&
+		!g.nextLoc.collidesWith(redSpawn) {
+		targetRow, targetCol = redSpawn.getCoords()
+	} else if mode == chase { // Chase mode targets (from the ghost's strategy)
+		targetRow, targetCol = g.strategy.ChaseTarget(g.game.view(), g.color)
+		chasing = true
 	} else if mode == scatter { // Scatter mode targets
 		targetRow, targetCol = g.scatterTarget.getCoords()
 	}
@@ -152,8 +197,8 @@ func (g *ghostState) plan() {
 	var moveDistSq [numDirs]int
 	for dir := uint8(0); dir < numDirs; dir++ {
 
-		// Get the neighboring cell in that location
-		row, col := g.nextLoc.getNeighborCoords(dir)
+		// Get the neighboring cell in that location (wrapping around portals)
+		row, col := g.game.wrapCoords(g.nextLoc.getNeighborCoords(dir))
 
 		// Calculate the distance from the target to the move location
 		moveDistSq[dir] = g.game.distSq(row, col, targetRow, targetCol)
@@ -161,11 +206,11 @@ func (g *ghostState) plan() {
 		// Determine if that move is valid
 		moveValid[dir] = !g.game.wallAt(row, col)
 
-		// Considerations when the ghost is spawning
-		if spawning {
+		// Considerations when the ghost is spawning (or entering as eyes)
+		if spawning || entering {
 
 			// Determine if the move would be within the ghost house
-			if g.game.ghostSpawnAt(row, col) {
+			if g.game.ghostHouseAt(row, col) {
 				moveValid[dir] = true
 			}
 
@@ -173,7 +218,7 @@ func (g *ghostState) plan() {
 				Determine if the move would help the ghost escape the ghost house,
 				and make it a valid one if so
 			*/
-			if row == ghostHouseExitRow && col == ghostHouseExitCol {
+			if g.game.ghostHouseExitAt(row, col) {
 				moveValid[dir] = true
 			}
 		}
@@ -183,6 +228,15 @@ func (g *ghostState) plan() {
 			moveValid[dir] = false
 		}
 
+		/*
+			As in the arcade game, ghosts can't turn upwards in restricted
+			zones of the maze (unless frightened, spawning, or eyes)
+		*/
+		if dir == up && frightSteps == 0 && !spawning && !eyes &&
+			g.game.noUpTurnAt(g.nextLoc.getCoords()) {
+			moveValid[dir] = false
+		}
+
 		// Increment the valid moves counter if necessary
 		if moveValid[dir] {
 			numValidMoves++
@@ -206,7 +260,7 @@ func (g *ghostState) plan() {
 	if frightSteps > 1 {
 
 		// Generate a random index out of the valid moves
-		randomNum := g.game.rng.Intn(numValidMoves)
+		randomNum := g.rng.intn(numValidMoves)
 
 		// Loop over all directions
 		for dir, count := uint8(0), 0; dir < numDirs; dir++ {
@@ -227,6 +281,16 @@ func (g *ghostState) plan() {
 		}
 	}
 
+	// If the ghost's strategy chooses its own directions, let it
+	if dirStrategy, ok := g.strategy.(GhostDirStrategy); ok && chasing {
+		dir, ok := dirStrategy.ChaseDir(g.game.view(), g.color,
+			newLocation(g.nextLoc), moveValid)
+		if ok && dir < numDirs && moveValid[dir] {
+			g.nextLoc.updateDir(dir)
+			return
+		}
+	}
+
 	// Otherwise, choose the best direction to reach the target
 	bestDir := up
 	bestDist := 0xffffffff // Some arbitrarily high number
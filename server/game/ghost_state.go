@@ -1,9 +1,5 @@
 package game
 
-import (
-	"sync"
-)
-
 // Enum-like declaration to hold the ghost colors
 const (
 	red       uint8 = 0
@@ -39,13 +35,22 @@ type ghostState struct {
 	loc           *locationState // Current location
 	nextLoc       *locationState // Planned location (for next update)
 	scatterTarget *locationState // Position of (fixed) scatter target
+	strategy      GhostStrategy  // Strategy for picking chase targets
 	game          *gameState     // The game state tied to the ghost
 	color         uint8
 	trappedSteps  uint8
 	frightSteps   uint8
-	spawning      bool         // Flag set when spawning
-	eaten         bool         // Flag set when eaten and returning to ghost house
-	muState       sync.RWMutex // Mutex to lock general state parameters
+	pelletCounter uint8
+	spawning      bool // Flag set when spawning
+	eaten         bool // Flag set when eaten and returning to ghost house
+	eyes          bool // Flag set when returning to ghost house as eyes
+	held          bool // Flag set when waiting for the pellet counters
+
+	/*
+		A random number generator for making frightened ghost decisions
+		(one per ghost, so that its decisions stay reproducible)
+	*/
+	rng randState
 }
 
 // Create a new ghost state with given location and color values
@@ -54,68 +59,117 @@ func newGhostState(_gameState *gameState, _color uint8) *ghostState {
 	// Ghost state object
 	g := ghostState{
 		loc:           newLocationStateCopy(emptyLoc),
-		nextLoc:       newLocationStateCopy(ghostSpawnLocs[_color]),
+		nextLoc:       newLocationStateCopy(_gameState.maze.ghostSpawns[_color]),
 		scatterTarget: newLocationStateCopy(ghostScatterTargets[_color]),
+		strategy:      ghostStrategies[_color],
 		game:          _gameState,
 		color:         _color,
 		trappedSteps:  ghostTrappedSteps[_color],
 		frightSteps:   0,
 		spawning:      true,
 		eaten:         false,
+		rng:           newRandState(_gameState.seed + int64(_color)),
 	}
 
 	// If the color is greater than the number of active ghosts, hide this ghost
 	if _color >= numActiveGhosts {
 		g.nextLoc = newLocationStateCopy(emptyLoc)
+	} else if _gameState.rules.PelletRelease {
+		// Otherwise, hold it in the ghost house if pellets release the ghosts
+		g.trappedSteps = 0
+		g.held = true
 	}
 
 	// Return the ghost state
 	return &g
 }
 
+/*
+Create a deep copy of a ghost state, tied to another game state (the other
+ghost's planned location, counters and random number generator are included)
+*/
+func (g *ghostState) clone(_gameState *gameState) *ghostState {
+
+	// Copy over the variables into a new ghost state
+	return &ghostState{
+		loc:           newLocationStateCopy(g.loc),
+		nextLoc:       newLocationStateCopy(g.nextLoc),
+		scatterTarget: newLocationStateCopy(g.scatterTarget),
+		strategy:      g.strategy,
+		game:          _gameState,
+		color:         g.color,
+		trappedSteps:  g.trappedSteps,
+		frightSteps:   g.frightSteps,
+		spawning:      g.spawning,
+		eaten:         g.eaten,
+		eyes:          g.eyes,
+		held:          g.held,
+		pelletCounter: g.pelletCounter,
+		rng:           g.rng,
+	}
+}
+
+/****************************** Ghost Move Timing *****************************/
+
+/*
+Get the number of ticks between the ghost's moves - ghosts move once per
+update period (with the game's steps), unless something changes their speed
+*/
+func (g *ghostState) getMovePeriod() uint8 {
+
+	// Start from the game's update period
+	period := int(g.game.getUpdatePeriod())
+
+	/*
+		Eyes move at their own speed - otherwise, frightened ghosts, and ghosts
+		in tunnels, slow down to the slowest speed that applies to them
+	*/
+	speed := 100
+	if g.isEyes() {
+		speed = int(g.game.rules.EyesSpeed)
+	} else {
+		if g.isFrightened() && !g.isEaten() {
+			speed = min(speed, int(g.game.rules.FrightSpeed))
+		}
+		if g.game.tunnelAt(g.loc.getCoords()) {
+			speed = min(speed, int(g.game.rules.GhostTunnelSpeed))
+		}
+	}
+	if speed != 100 {
+		period = (period*100 + speed/2) / speed
+	} else if g.color == red && g.game.elroyStage > 0 && !g.isEyes() {
+		// Otherwise, with Cruise Elroy, red moves faster at each stage
+		period -= int(g.game.elroyStage) * int(g.game.rules.ElroySpeedup)
+	}
+
+	// Ghosts can move at most once per tick
+	return uint8(min(max(1, period), 255))
+}
+
+// Check if the ghost moves at the current tick
+func (g *ghostState) moveReady() bool {
+	return g.game.getCurrTicks()%uint32(g.getMovePeriod()) == 0
+}
+
 /*************************** Ghost Frightened State ***************************/
 
 // Set the fright steps of a ghost
 func (g *ghostState) setFrightSteps(steps uint8) {
-
-	// (Write) lock the ghost state
-	g.muState.Lock()
-	{
-		g.frightSteps = steps
-	}
-	g.muState.Unlock()
+	g.frightSteps = steps
 }
 
 // Decrement the fright steps of a ghost
 func (g *ghostState) decFrightSteps() {
-
-	// (Write) lock the ghost state
-	g.muState.Lock()
-	{
-		g.frightSteps--
-	}
-	g.muState.Unlock()
+	g.frightSteps--
 }
 
 // Get the fright steps of a ghost
 func (g *ghostState) getFrightSteps() uint8 {
-
-	// (Read) lock the ghost state
-	g.muState.RLock()
-	defer g.muState.RUnlock()
-
-	// Return the current fright steps
 	return g.frightSteps
 }
 
 // Check if a ghost is frightened
 func (g *ghostState) isFrightened() bool {
-
-	// (Read) lock the ghost state
-	g.muState.RLock()
-	defer g.muState.RUnlock()
-
-	// Return whether there is at least one fright step left
 	return g.frightSteps > 0
 }
 
@@ -123,58 +177,62 @@ func (g *ghostState) isFrightened() bool {
 
 // Set the trapped steps of a ghost
 func (g *ghostState) setTrappedSteps(steps uint8) {
-
-	// (Write) lock the ghost state
-	g.muState.Lock()
-	{
-		g.trappedSteps = steps
-	}
-	g.muState.Unlock()
+	g.trappedSteps = steps
 }
 
 // Decrement the trapped steps of a ghost
 func (g *ghostState) decTrappedSteps() {
+	g.trappedSteps--
+}
 
-	// (Write) lock the ghost state
-	g.muState.Lock()
-	{
-		g.trappedSteps--
-	}
-	g.muState.Unlock()
+// Get the trapped steps of a ghost
+func (g *ghostState) getTrappedSteps() uint8 {
+	return g.trappedSteps
 }
 
-// Check if a ghost is trapped
+// Check if a ghost is trapped (for some steps, or held in the ghost house)
 func (g *ghostState) isTrapped() bool {
+	return g.trappedSteps > 0 || g.held
+}
+
+/***************************** Ghost Release State ****************************/
+
+// Set whether a ghost is held in the ghost house
+func (g *ghostState) setHeld(held bool) {
+	g.held = held
+}
+
+// Check if a ghost is held in the ghost house, waiting for the pellet counters
+func (g *ghostState) isHeld() bool {
+	return g.held
+}
 
-	// (Read) lock the ghost state
-	g.muState.RLock()
-	defer g.muState.RUnlock()
+// Set the pellet counter of a ghost
+func (g *ghostState) setPelletCounter(count uint8) {
+	g.pelletCounter = count
+}
 
-	// Return whether there is at least one fright step left
-	return g.trappedSteps > 0
+// Increment the pellet counter of a ghost (stopping at the maximum)
+func (g *ghostState) incPelletCounter() {
+	if g.pelletCounter < 255 {
+		g.pelletCounter++
+	}
+}
+
+// Get the pellet counter of a ghost
+func (g *ghostState) getPelletCounter() uint8 {
+	return g.pelletCounter
 }
 
 /**************************** Ghost Spawning State ****************************/
 
 // Set the ghost spawning flag
 func (g *ghostState) setSpawning(spawning bool) {
-
-	// (Write) lock the ghost state
-	g.muState.Lock()
-	{
-		g.spawning = spawning
-	}
-	g.muState.Unlock()
+	g.spawning = spawning
 }
 
 // Check if a ghost is spawning
 func (g *ghostState) isSpawning() bool {
-
-	// (Read) lock the ghost state
-	g.muState.RLock()
-	defer g.muState.RUnlock()
-
-	// Return the current ghost spawning flag
 	return g.spawning
 }
 
@@ -182,22 +240,33 @@ func (g *ghostState) isSpawning() bool {
 
 // Set the ghost eaten flag
 func (g *ghostState) setEaten(eaten bool) {
-
-	// (Write) lock the ghost state
-	g.muState.Lock()
-	{
-		g.eaten = eaten
-	}
-	g.muState.Unlock()
+	g.eaten = eaten
 }
 
 // Check if a ghost is eaten
 func (g *ghostState) isEaten() bool {
+	return g.eaten
+}
 
-	// (Read) lock the ghost state
-	g.muState.RLock()
-	defer g.muState.RUnlock()
+/****************************** Ghost Eyes Flag *******************************/
 
-	// Return the current ghost eaten flag
-	return g.eaten
+// Set the ghost eyes flag
+func (g *ghostState) setEyes(eyes bool) {
+	g.eyes = eyes
+}
+
+// Check if a ghost is returning to the ghost house as eyes
+func (g *ghostState) isEyes() bool {
+	return g.eyes
+}
+
+/*
+Get the location that the ghost comes back to life at after being eaten - its
+spawn point (or pink's spawn point, in the case of red, so it is in the box)
+*/
+func (g *ghostState) respawnLoc() *locationState {
+	if g.color == red {
+		return g.game.maze.ghostSpawns[pink]
+	}
+	return g.game.maze.ghostSpawns[g.color]
 }
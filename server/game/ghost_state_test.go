@@ -0,0 +1,101 @@
+package game
+
+import "testing"
+
+// Check that ghosts in tunnels slow down to the configured tunnel speed
+func TestGhostTunnelSpeed(t *testing.T) {
+
+	// Play on a maze with tunnels, with ghosts at half speed in them
+	prevMaze := currMaze
+	t.Cleanup(func() { currMaze = prevMaze })
+	if err := ConfigMazeFile("mazes/tunnels.txt"); err != nil {
+		t.Fatal(err)
+	}
+	configTestRules(t, func(rules *Rules) { rules.GhostTunnelSpeed = 50 })
+
+	// Out of the tunnel, red moves at the usual speed
+	gs := newGameState(1)
+	ghost := gs.ghosts[red]
+	updatePeriod := gs.getUpdatePeriod()
+	ghost.loc.updateCoords(14, 6)
+	if period := ghost.getMovePeriod(); period != updatePeriod {
+		t.Fatalf("move period %d outside a tunnel, expected %d",
+			period, updatePeriod)
+	}
+
+	// In the tunnel, red takes twice as long to move
+	ghost.loc.updateCoords(14, 3)
+	if period := ghost.getMovePeriod(); period != 2*updatePeriod {
+		t.Fatalf("move period %d in a tunnel, expected %d",
+			period, 2*updatePeriod)
+	}
+}
+
+/*
+Check that on the arcade maze, ghosts can't turn upwards in the restricted
+zones ('-' and ',' cells) while scattering or chasing, but can when
+frightened, spawning, or eyes
+*/
+func TestNoUpTurns(t *testing.T) {
+
+	// Play on the arcade maze
+	prevMaze := currMaze
+	t.Cleanup(func() { currMaze = prevMaze })
+	if err := ConfigMazeFile("mazes/arcade.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	/*
+		Each ghost comes from the left into a restricted cell with open space
+		above and to the right (and a wall below), with its target above it
+	*/
+	tests := []struct {
+		name   string
+		row    int8
+		mode   uint8
+		modify func(g *ghostState)
+		up     bool
+	}{
+		{"chase", 11, chase, func(g *ghostState) {}, false},
+		{"scatter", 11, scatter, func(g *ghostState) {}, false},
+		{"chase on a pellet", 23, chase, func(g *ghostState) {}, false},
+		{"scatter on a pellet", 23, scatter, func(g *ghostState) {}, false},
+		{"frightened", 23, chase, func(g *ghostState) { g.setFrightSteps(1) },
+			true},
+		{"spawning", 23, chase, func(g *ghostState) { g.setSpawning(true) },
+			true},
+		{"eyes", 23, chase, func(g *ghostState) {
+			g.setEaten(true)
+			g.setEyes(true)
+		}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gs := newGameState(1)
+			gs.setMode(test.mode)
+			gs.pacmanLoc.updateCoords(1, 12)
+			ghost := gs.ghosts[pink]
+			ghost.scatterTarget.updateCoords(0, 12)
+			ghost.setTrappedSteps(0)
+			ghost.setSpawning(false)
+			ghost.loc.updateCoords(test.row, 11)
+			ghost.loc.updateDir(right)
+			test.modify(ghost)
+			if !gs.noUpTurnAt(test.row, 12) {
+				t.Fatalf("(%d, 12) should be a restricted cell", test.row)
+			}
+
+			// Plan the move out of the restricted cell
+			ghost.plan()
+			expected := right
+			if test.up {
+				expected = up
+			}
+			if dir := ghost.nextLoc.getDir(); dir != expected {
+				t.Fatalf("ghost heads %s from (%d, 12), expected %s",
+					dirNames[dir], test.row, dirNames[expected])
+			}
+		})
+	}
+}
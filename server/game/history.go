@@ -0,0 +1,136 @@
+package game
+
+import (
+	"log"
+	"strconv"
+)
+
+/*
+A history object, to keep a ring buffer of game state snapshots taken at
+each update boundary, so that a paused game can be rewound
+*/
+type stateHistory struct {
+	states []*gameState // Ring buffer of snapshots
+	newest int          // Index of the newest snapshot
+	size   int          // Number of snapshots in the buffer
+}
+
+// Create a new state history, holding up to a given number of snapshots
+func newStateHistory(capacity int) *stateHistory {
+	return &stateHistory{
+		states: make([]*gameState, capacity),
+		newest: -1,
+		size:   0,
+	}
+}
+
+// Take a snapshot of the game state, replacing the oldest one if full
+func (h *stateHistory) push(gs *gameState) {
+
+	// A nil history keeps no snapshots
+	if h == nil {
+		return
+	}
+
+	// Write the snapshot after the newest one
+	h.newest = (h.newest + 1) % len(h.states)
+	h.states[h.newest] = gs.clone()
+	h.size = min(h.size+1, len(h.states))
+}
+
+// Remove the newest snapshot
+func (h *stateHistory) pop() {
+	h.states[h.newest] = nil
+	h.newest = (h.newest - 1 + len(h.states)) % len(h.states)
+	h.size--
+}
+
+// Remove all snapshots (after a reset, the old game can't be rewound to)
+func (h *stateHistory) clear() {
+
+	// A nil history keeps no snapshots
+	if h == nil {
+		return
+	}
+
+	// Remove each snapshot
+	for h.size > 0 {
+		h.pop()
+	}
+}
+
+/*
+Rewind a given number of updates before the current tick, returning a (paused)
+copy of the snapshot - snapshots newer than it are discarded, so that the game
+can continue from there. Returns nil if there is nothing to rewind to
+*/
+func (h *stateHistory) rewind(gs *gameState, updates int) *gameState {
+
+	// A nil history keeps no snapshots
+	if h == nil {
+		return nil
+	}
+
+	// Discard any snapshots from the current tick (or later)
+	currTicks := gs.getCurrTicks()
+	for h.size > 0 && h.states[h.newest].getCurrTicks() >= currTicks {
+		h.pop()
+	}
+
+	// Go back the requested number of updates, keeping at least one snapshot
+	for ; updates > 1 && h.size > 1; updates-- {
+		h.pop()
+	}
+
+	// If there is nothing left, there is nothing to rewind to
+	if h.size == 0 {
+		return nil
+	}
+
+	// Restore a copy of the snapshot, paused
+	restored := h.states[h.newest].clone()
+	restored.pause()
+	return restored
+}
+
+/*
+Interpret a rewind command ('u', optionally followed by a decimal number of
+updates to go back), returning the restored game state (or nil, if the
+command is invalid or there is nothing to rewind to)
+*/
+func (h *stateHistory) interpretRewind(gs *gameState, msg []byte) *gameState {
+
+	// Parse the number of updates (one by default)
+	updates := 1
+	if len(msg) > 1 {
+		var err error
+		updates, err = strconv.Atoi(string(msg[1:]))
+		if err != nil || updates <= 0 {
+			log.Printf("\033[35m\033[1mERR:  Invalid rewind count %q "+
+				"(message type 'u'). Ignoring...\033[0m\n", msg[1:])
+			return nil
+		}
+	}
+
+	// Only allow rewinding while paused
+	if !gs.isPaused() {
+		log.Println("\033[35mWARN: The game can only be rewound while " +
+			"paused. Ignoring...\033[0m")
+		return nil
+	}
+
+	// Rewind the game
+	restored := h.rewind(gs, updates)
+	if restored == nil {
+		log.Println("\033[35mWARN: No earlier updates to rewind to. " +
+			"Ignoring...\033[0m")
+		return nil
+	}
+
+	// Log the rewind to the terminal
+	log.Printf("\033[32mGAME: Rewound (t = %d -> %d)\033[0m\n",
+		gs.getCurrTicks(), restored.getCurrTicks())
+
+	// Return the restored game state
+	return restored
+}
@@ -0,0 +1,140 @@
+package game
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+/*
+Check that rewinding a paused game restores the exact frame serialized at
+the earlier update (apart from the mode, which is paused after rewinding)
+*/
+func TestRewindRestoresFrame(t *testing.T) {
+
+	// Play for a while, keeping the frame serialized at each update
+	sim := NewSimulator(7)
+	updateFrames := make(map[uint32][]byte)
+	var updateTicks []uint32
+	for step := 0; step < 400; step++ {
+		if cmd, ok := testCommands[step]; ok {
+			sim.Step([]byte(cmd))
+		} else {
+			sim.Step()
+		}
+		ticks := sim.Ticks()
+		if sim.Mode() != paused && ticks%uint32(sim.UpdatePeriod()) == 0 {
+			if _, ok := updateFrames[ticks]; !ok {
+				updateTicks = append(updateTicks, ticks)
+			}
+			updateFrames[ticks] = sim.Snapshot()
+		}
+	}
+
+	// Pause between updates, so the last update is one update back
+	for sim.Ticks()%uint32(sim.UpdatePeriod()) == 0 {
+		sim.Step()
+	}
+	sim.Step([]byte("p"))
+
+	// Rewind three updates, and compare against the frame from back then
+	sim.Step([]byte("u3"))
+	want := updateTicks[len(updateTicks)-3]
+	if sim.Ticks() != want {
+		t.Fatalf("rewound to t = %d, expected t = %d", sim.Ticks(), want)
+	}
+	got, expected := sim.Snapshot(), updateFrames[want]
+	if got[serGameModeIdx()] != paused {
+		t.Fatalf("rewound game is not paused (mode %d)", got[serGameModeIdx()])
+	}
+	got[serGameModeIdx()] = expected[serGameModeIdx()]
+	if !bytes.Equal(got, expected) {
+		t.Fatalf("rewound frame differs from the frame at t = %d", want)
+	}
+
+	// Rewind once more, which should go back one further update
+	sim.Step([]byte("u"))
+	want = updateTicks[len(updateTicks)-4]
+	if sim.Ticks() != want {
+		t.Fatalf("rewound to t = %d, expected t = %d", sim.Ticks(), want)
+	}
+	got, expected = sim.Snapshot(), updateFrames[want]
+	got[serGameModeIdx()] = expected[serGameModeIdx()]
+	if !bytes.Equal(got, expected) {
+		t.Fatalf("rewound frame differs from the frame at t = %d", want)
+	}
+}
+
+/*
+Play a scripted game on a game engine, then check that rewinding it sends the
+restored frame (matching the frame sent at the earlier update) right away,
+without waiting for the engine's clock
+*/
+func checkEngineRewind(t *testing.T, ge *GameEngine, inputCh chan<- []byte,
+	outputCh <-chan []byte) {
+	t.Helper()
+
+	// Play for a while, keeping the frame sent at each update
+	updateFrames := make(map[uint32][]byte)
+	for step := 0; step < 400; step++ {
+		if cmd, ok := testCommands[step]; ok {
+			inputCh <- []byte(cmd)
+		}
+		update := ge.justTicked && ge.state.updateReady()
+		ticks := ge.state.getCurrTicks()
+		ge.runFrame()
+		if frame := <-outputCh; update {
+			updateFrames[ticks] = frame
+		}
+	}
+
+	// Pause between updates, then send the paused frame
+	for ge.state.updateReady() {
+		ge.runFrame()
+		<-outputCh
+	}
+	inputCh <- []byte("p")
+	ge.runFrame()
+	<-outputCh
+	nextEngineFrame(t, ge, outputCh)
+
+	// Rewind three updates - the next frame should be the one from back then
+	inputCh <- []byte("u3")
+	ge.runFrame()
+	<-outputCh
+	got := nextEngineFrame(t, ge, outputCh)
+	expected, ok := updateFrames[ge.state.getCurrTicks()]
+	if !ok {
+		t.Fatalf("rewound to t = %d, which was not an update",
+			ge.state.getCurrTicks())
+	}
+	if got[serGameModeIdx()] != paused {
+		t.Fatalf("rewound frame is not paused (mode %d)", got[serGameModeIdx()])
+	}
+	got = bytes.Clone(got)
+	got[serGameModeIdx()] = expected[serGameModeIdx()]
+	if !bytes.Equal(got, expected) {
+		t.Fatalf("rewound frame differs from the frame sent at t = %d",
+			ge.state.getCurrTicks())
+	}
+}
+
+// Check that rewinding a fast-forward engine sends the restored frame
+func TestFastForwardEngineRewind(t *testing.T) {
+	inputCh := make(chan []byte, 10)
+	outputCh := make(chan []byte, 1)
+	ge := NewHeadlessGameEngine(outputCh, inputCh, &sync.WaitGroup{}, false)
+	checkEngineRewind(t, ge, inputCh, outputCh)
+}
+
+/*
+Check that rewinding a real-time engine sends the restored frame without
+waiting for the next tick (its ticker is stopped, so it would never come)
+*/
+func TestRealTimeEngineRewind(t *testing.T) {
+	inputCh := make(chan []byte, 10)
+	outputCh := make(chan []byte, 1)
+	ge := NewGameEngine(outputCh, inputCh, &sync.WaitGroup{}, 1)
+	ge.ticker.Stop()
+	checkEngineRewind(t, ge, inputCh, outputCh)
+}
@@ -1,7 +1,5 @@
 package game
 
-import "sync"
-
 // Directions:                U   L   D   R  None
 var dRow [5]int8 = [...]int8{-1, -0, +1, +0, +0}
 var dCol [5]int8 = [...]int8{-0, -1, +0, +1, +0}
@@ -32,7 +30,6 @@ type locationState struct {
 	row int8  // Row
 	col int8  // Col
 	dir uint8 // Index of the direction, within the direction arrays
-	sync.RWMutex
 }
 
 // Create a new location state with given position and direction values
@@ -47,10 +44,6 @@ func newLocationState(_row int8, _col int8, _dir uint8) *locationState {
 // Create a new location state as a copy-by-value of an existing one
 func newLocationStateCopy(_loc *locationState) *locationState {
 
-	// Just to be thread safe, (read) lock the other state
-	_loc.RLock()
-	defer _loc.RUnlock()
-
 	// Copy over the variables into a new location state
 	return &locationState{
 		row: _loc.row,
@@ -64,14 +57,6 @@ func newLocationStateCopy(_loc *locationState) *locationState {
 // Determine if another location state matches with the given location
 func (loc *locationState) collidesWith(loc2 *locationState) bool {
 
-	// (Read) lock the states (to prevent other reads or writes)
-	loc.RLock()
-	loc2.RLock()
-	defer func() {
-		loc.RUnlock()
-		loc2.RUnlock()
-	}()
-
 	// If any of the rows or columns is at least 32, they don't collide
 	if loc.row >= 32 || loc.col >= 32 || loc2.row >= 32 || loc2.col >= 32 {
 		return false
@@ -84,12 +69,6 @@ func (loc *locationState) collidesWith(loc2 *locationState) bool {
 // Determine if a given location state matches with the empty location
 func (loc *locationState) isEmpty() bool {
 
-	// (Read) lock the states (to prevent other reads or writes)
-	loc.RLock()
-	defer func() {
-		loc.RUnlock()
-	}()
-
 	// Return if both coordinates match
 	return ((loc.row == emptyLoc.row) && (loc.col == emptyLoc.col))
 }
@@ -97,10 +76,6 @@ func (loc *locationState) isEmpty() bool {
 // Return a direction corresponding to an existing location
 func (loc *locationState) getDir() uint8 {
 
-	// Lock the states for thread safety
-	loc.RLock()
-	defer loc.RUnlock()
-
 	// Return the direction
 	return loc.dir
 }
@@ -128,10 +103,6 @@ func (loc *locationState) getReversedDir() uint8 {
 // Return a set of coordinates corresponding to an existing location
 func (loc *locationState) getCoords() (int8, int8) {
 
-	// Lock the states for thread safety
-	loc.RLock()
-	defer loc.RUnlock()
-
 	// Return the pair of coordinates
 	return (loc.row),
 		(loc.col)
@@ -140,10 +111,6 @@ func (loc *locationState) getCoords() (int8, int8) {
 // Create a new set of coordinates as the neighbor of an existing location
 func (loc *locationState) getNeighborCoords(dir uint8) (int8, int8) {
 
-	// Lock the states for thread safety
-	loc.RLock()
-	defer loc.RUnlock()
-
 	// Add the deltas to the coordinates and return the pair
 	return (loc.row + dRow[dir]),
 		(loc.col + dCol[dir])
@@ -155,10 +122,6 @@ of a given location state
 */
 func (loc *locationState) getAheadCoords(spaces int8) (int8, int8) {
 
-	// Lock the states for thread safety
-	loc.RLock()
-	defer loc.RUnlock()
-
 	// Add the deltas to the coordinates and return the pair
 	return (loc.row + dRow[loc.dir]*spaces),
 		(loc.col + dCol[loc.dir]*spaces)
@@ -192,10 +155,6 @@ func (loc *locationState) advanceFrom(loc2 *locationState) {
 // Copy all the variables from another location state into the given location
 func (loc *locationState) updateDir(dir uint8) {
 
-	// Lock the state for thread safety
-	loc.Lock()
-	defer loc.Unlock()
-
 	// Update the values
 	loc.dir = dir
 }
@@ -203,10 +162,6 @@ func (loc *locationState) updateDir(dir uint8) {
 // Move a given location state to specified coordinates
 func (loc *locationState) updateCoords(row int8, col int8) {
 
-	// Lock the state for thread safety
-	loc.Lock()
-	defer loc.Unlock()
-
 	// Update the values
 	loc.row = row
 	loc.col = col
@@ -0,0 +1,396 @@
+package game
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"os"
+)
+
+/*
+The characters of a maze file - each line of the file is a row of the maze
+(starting from row 0), and each character of a line is a cell in that row
+(starting from column 0). Warp portals come in pairs, on opposite edges of
+the maze - moving off the edge of the maze from one leads to the other
+*/
+const (
+	mazeWall        byte = '#' // Wall
+	mazeEmpty       byte = ' ' // Empty space
+	mazePellet      byte = '.' // Pellet
+	mazeSuperPellet byte = 'o' // Super pellet
+	mazePacman      byte = 'P' // Pacman spawn (empty space)
+	mazeFruit       byte = 'F' // Fruit spawn (empty space)
+	mazeGhostHouse  byte = '_' // Ghost house (a wall to all but spawning ghosts)
+	mazeGhostExit   byte = '=' // Ghost house exit (same as above)
+	mazeTunnel      byte = '~' // Tunnel (empty space, where ghosts may slow down)
+	mazePortal      byte = '@' // Warp portal (tunnel, at the edge of the maze)
+	mazeNoUp        byte = '-' // Empty space where ghosts can't turn upwards
+	mazeNoUpPellet  byte = ',' // Pellet where ghosts can't turn upwards
+)
+
+/*
+Ghost spawns are marked by the digits '0' to '3' (one for each ghost color,
+in order) - a spawn within the bounds of the ghost house is part of the ghost
+house, while any other spawn is empty space
+*/
+const mazeGhostSpawn byte = '0'
+
+// The default maze, used unless a maze file is configured
+//
+//go:embed mazes/default.txt
+var defaultMazeFile []byte
+
+/*
+A maze layout object, to hold the features of a maze (read from a maze file)
+that a new game starts with - it isn't modified once loaded, so game states
+can share it. Mazes smaller than mazeRows x mazeCols are padded with walls
+*/
+type mazeLayout struct {
+	rows           int8             // Number of rows (before padding)
+	cols           int8             // Number of columns (before padding)
+	walls          [mazeRows]uint32 // Walls (including the ghost house)
+	pellets        [mazeRows]uint32 // Pellets (including super pellets)
+	superPellets   [mazeRows]uint32 // Super pellets
+	ghostHouse     [mazeRows]uint32 // Ghost house cells (excluding the exit)
+	tunnels        [mazeRows]uint32 // Tunnel cells (including warp portals)
+	portals        [mazeRows]uint32 // Warp portal cells
+	noUpTurns      [mazeRows]uint32 // Cells where ghosts can't turn upwards
+	numPellets     uint16           // Number of pellets
+	pacmanSpawn    *locationState   // Spawn location of Pacman
+	fruitSpawn     *locationState   // Spawn location of the fruit
+	ghostHouseExit *locationState   // Location of the ghost house exit
+
+	// Spawn locations of the ghosts
+	ghostSpawns [numColors]*locationState
+
+	// Rows of the maze file (without line endings), to report to clients
+	text []string
+}
+
+// The maze that new games are played on
+var currMaze *mazeLayout = mustParseMaze(defaultMazeFile)
+
+/*
+Configure the maze that new games are played on, by loading a maze file
+(an empty path keeps the default maze)
+*/
+func ConfigMazeFile(path string) error {
+
+	// An empty path keeps the default maze
+	if path == "" {
+		return nil
+	}
+
+	// Read the maze file
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// Parse the maze, prefixing any errors with the path
+	maze, err := parseMaze(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	// Use the new maze
+	currMaze = maze
+	return nil
+}
+
+/*
+The layout of a maze, as reported to clients - the rows of its maze file, its
+walls as a bit array per row (in the same form as the pellets of a frame, with
+padding walled off), and the spawn locations ([row, col]) of the ghosts (red's
+is just outside the ghost house exit, where eyes head for to get back in)
+*/
+type MazeInfo struct {
+	Text        []string
+	Walls       [mazeRows]uint32
+	GhostSpawns [numColors][2]int8
+}
+
+// Get the layout of the maze that new games are played on
+func CurrentMaze() *MazeInfo {
+	info := MazeInfo{
+		Text:  currMaze.text,
+		Walls: currMaze.walls,
+	}
+	for color, spawn := range currMaze.ghostSpawns {
+		row, col := spawn.getCoords()
+		info.GhostSpawns[color] = [2]int8{row, col}
+	}
+	return &info
+}
+
+// Parse a built-in maze, which should never fail
+func mustParseMaze(data []byte) *mazeLayout {
+	maze, err := parseMaze(data)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in maze: %s", err))
+	}
+	return maze
+}
+
+/*
+Parse the contents of a maze file into a maze layout, returning an error
+(with the line and column, where possible) if the maze is invalid
+*/
+func parseMaze(data []byte) (*mazeLayout, error) {
+
+	// Split the file into lines, ignoring line endings and trailing blank lines
+	lines := bytes.Split(data, []byte("\n"))
+	for idx := range lines {
+		lines[idx] = bytes.TrimSuffix(lines[idx], []byte("\r"))
+	}
+	for len(lines) > 0 && len(bytes.TrimSpace(lines[len(lines)-1])) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+
+	// Make sure the maze has a size that can be serialized
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("empty maze")
+	}
+	if len(lines) > int(mazeRows) {
+		return nil, fmt.Errorf("line %d: too many rows (at most %d)",
+			mazeRows+1, mazeRows)
+	}
+	numCols := len(lines[0])
+	for idx, line := range lines {
+		if len(line) > int(mazeCols) {
+			return nil, fmt.Errorf("line %d, column %d: too many columns "+
+				"(at most %d)", idx+1, mazeCols+1, mazeCols)
+		}
+		if len(line) != numCols {
+			return nil, fmt.Errorf("line %d: %d columns, expected %d "+
+				"(the same as line 1)", idx+1, len(line), numCols)
+		}
+	}
+
+	/*
+		Find the bounds of the ghost house first, as ghost spawns within them
+		are part of the ghost house
+	*/
+	houseTop, houseLeft := len(lines), numCols
+	houseBottom, houseRight := -1, -1
+	for row, line := range lines {
+		for col, char := range line {
+			if char == mazeGhostHouse {
+				houseTop, houseBottom = min(houseTop, row), max(houseBottom, row)
+				houseLeft, houseRight = min(houseLeft, col), max(houseRight, col)
+			}
+		}
+	}
+	inHouse := func(row int, col int) bool {
+		return row >= houseTop && row <= houseBottom &&
+			col >= houseLeft && col <= houseRight
+	}
+
+	// Start with every cell as a wall (so any padding is walled off)
+	maze := mazeLayout{
+		rows: int8(len(lines)),
+		cols: int8(numCols),
+	}
+	for _, line := range lines {
+		maze.text = append(maze.text, string(line))
+	}
+	for row := range maze.walls {
+		maze.walls[row] = 0xffffffff
+	}
+
+	// Keep track of where each spawn was first found, to catch duplicates
+	type cell struct{ line, col int }
+	found := make(map[string]cell)
+	spawnAt := func(name string, row int, col int) error {
+		if prev, ok := found[name]; ok {
+			return fmt.Errorf("line %d, column %d: duplicate %s (first at "+
+				"line %d, column %d)", row+1, col+1, name, prev.line, prev.col)
+		}
+		found[name] = cell{row + 1, col + 1}
+		return nil
+	}
+
+	// Loop over each cell of the maze
+	for row, line := range lines {
+		for col, char := range line {
+
+			// Shorthand for the coordinates of the cell
+			r, c := int8(row), int8(col)
+
+			// Assume the cell is empty space unless it says otherwise
+			wall := false
+
+			switch {
+			case char == mazeWall:
+				wall = true
+			case char == mazeEmpty:
+			case char == mazeTunnel:
+				modifyBit(&maze.tunnels[row], c, true)
+			case char == mazePortal:
+				modifyBit(&maze.tunnels[row], c, true)
+				modifyBit(&maze.portals[row], c, true)
+			case char == mazeNoUp:
+				modifyBit(&maze.noUpTurns[row], c, true)
+			case char == mazePellet:
+				modifyBit(&maze.pellets[row], c, true)
+				maze.numPellets++
+			case char == mazeNoUpPellet:
+				modifyBit(&maze.noUpTurns[row], c, true)
+				modifyBit(&maze.pellets[row], c, true)
+				maze.numPellets++
+			case char == mazeSuperPellet:
+				modifyBit(&maze.pellets[row], c, true)
+				modifyBit(&maze.superPellets[row], c, true)
+				maze.numPellets++
+			case char == mazePacman:
+				if err := spawnAt("Pacman spawn", row, col); err != nil {
+					return nil, err
+				}
+				maze.pacmanSpawn = newLocationState(r, c, pacmanSpawnDir)
+			case char == mazeFruit:
+				if err := spawnAt("fruit spawn", row, col); err != nil {
+					return nil, err
+				}
+				maze.fruitSpawn = newLocationState(r, c, none)
+			case char == mazeGhostHouse:
+				wall = true
+				modifyBit(&maze.ghostHouse[row], c, true)
+			case char == mazeGhostExit:
+				if err := spawnAt("ghost house exit", row, col); err != nil {
+					return nil, err
+				}
+				wall = true
+				maze.ghostHouseExit = newLocationState(r, c, none)
+			case char >= mazeGhostSpawn && char < mazeGhostSpawn+numColors:
+				color := char - mazeGhostSpawn
+				name := fmt.Sprintf("%s ghost spawn", ghostNames[color])
+				if err := spawnAt(name, row, col); err != nil {
+					return nil, err
+				}
+				maze.ghostSpawns[color] = newLocationState(r, c,
+					ghostSpawnDirs[color])
+				if inHouse(row, col) {
+					wall = true
+					modifyBit(&maze.ghostHouse[row], c, true)
+				}
+			default:
+				return nil, fmt.Errorf("line %d, column %d: unknown character %q",
+					row+1, col+1, char)
+			}
+
+			// Clear the wall bit, if the cell is not a wall
+			if !wall {
+				modifyBit(&maze.walls[row], c, false)
+			}
+		}
+	}
+
+	// Make sure that every spawn (and the ghost house exit) was found
+	if maze.pacmanSpawn == nil {
+		return nil, fmt.Errorf("missing Pacman spawn ('%c')", mazePacman)
+	}
+	if maze.fruitSpawn == nil {
+		return nil, fmt.Errorf("missing fruit spawn ('%c')", mazeFruit)
+	}
+	if maze.ghostHouseExit == nil {
+		return nil, fmt.Errorf("missing ghost house exit ('%c')", mazeGhostExit)
+	}
+	for color, spawn := range maze.ghostSpawns {
+		if spawn == nil {
+			return nil, fmt.Errorf("missing %s ghost spawn ('%c')",
+				ghostNames[color], mazeGhostSpawn+byte(color))
+		}
+	}
+
+	// Make sure that each warp portal is paired with another one
+	for row := int8(0); row < maze.rows; row++ {
+		for col := int8(0); col < maze.cols; col++ {
+			if err := maze.checkPortal(row, col); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Make sure there is something to collect
+	if maze.numPellets == 0 {
+		return nil, fmt.Errorf("no pellets ('%c' or '%c')",
+			mazePellet, mazeSuperPellet)
+	}
+
+	// Return the maze layout
+	return &maze, nil
+}
+
+/*
+Check that a warp portal (if there is one at a given location) is on an edge
+of the maze, with another warp portal opposite it
+*/
+func (maze *mazeLayout) checkPortal(row int8, col int8) error {
+
+	// If there is no portal here, there is nothing to check
+	if !maze.portalAt(row, col) {
+		return nil
+	}
+
+	// Find the location of the opposite portal
+	oppRow, oppCol := row, col
+	onRowEdge := row == 0 || row == maze.rows-1
+	onColEdge := col == 0 || col == maze.cols-1
+	switch {
+	case onRowEdge && onColEdge:
+		return fmt.Errorf("line %d, column %d: warp portal in a corner",
+			row+1, col+1)
+	case onRowEdge:
+		oppRow = maze.rows - 1 - row
+	case onColEdge:
+		oppCol = maze.cols - 1 - col
+	default:
+		return fmt.Errorf("line %d, column %d: warp portal not on an edge",
+			row+1, col+1)
+	}
+
+	// Make sure the opposite portal exists
+	if !maze.portalAt(oppRow, oppCol) {
+		return fmt.Errorf("line %d, column %d: warp portal without a pair "+
+			"(expected one at line %d, column %d)", row+1, col+1,
+			oppRow+1, oppCol+1)
+	}
+	return nil
+}
+
+// Determines if a warp portal is at a given location
+func (maze *mazeLayout) portalAt(row int8, col int8) bool {
+	if row < 0 || row >= maze.rows || col < 0 || col >= maze.cols {
+		return false
+	}
+	return getBit(maze.portals[row], col)
+}
+
+/*
+Wrap a location just off the edge of the maze around to the opposite edge,
+if there is a warp portal there - otherwise (or if the location is not just
+off the edge), it is returned as-is
+*/
+func (maze *mazeLayout) wrapCoords(row int8, col int8) (int8, int8) {
+
+	// Find the location on the opposite edge
+	wrapRow, wrapCol := row, col
+	switch {
+	case row == -1:
+		wrapRow = maze.rows - 1
+	case row == maze.rows:
+		wrapRow = 0
+	case col == -1:
+		wrapCol = maze.cols - 1
+	case col == maze.cols:
+		wrapCol = 0
+	default:
+		return row, col
+	}
+
+	// Only wrap around if there is a warp portal to arrive at
+	if !maze.portalAt(wrapRow, wrapCol) {
+		return row, col
+	}
+	return wrapRow, wrapCol
+}
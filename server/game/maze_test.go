@@ -0,0 +1,204 @@
+package game
+
+import (
+	"bytes"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Copy the default maze, changing a given cell (by line and column)
+func editDefaultMaze(line int, col int, char byte) []byte {
+	lines := bytes.Split(bytes.Clone(defaultMazeFile), []byte("\n"))
+	lines[line-1][col-1] = char
+	return bytes.Join(lines, []byte("\n"))
+}
+
+/*
+Check that parsing a maze rejects bad input, pointing to the line and column
+of the problem
+*/
+func TestParseMazeErrors(t *testing.T) {
+
+	// A maze with one short line
+	lines := bytes.Split(bytes.Clone(defaultMazeFile), []byte("\n"))
+	lines[4] = lines[4][:len(lines[4])-1]
+	shortLine := bytes.Join(lines, []byte("\n"))
+
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{
+			name: "unknown character",
+			data: editDefaultMaze(2, 2, 'X'),
+			err:  "line 2, column 2: unknown character 'X'",
+		},
+		{
+			name: "duplicate spawn",
+			data: editDefaultMaze(30, 2, mazePacman),
+			err: "line 30, column 2: duplicate Pacman spawn " +
+				"(first at line 24, column 14)",
+		},
+		{
+			name: "duplicate ghost spawn",
+			data: editDefaultMaze(2, 3, mazeGhostSpawn),
+			err: "line 12, column 14: duplicate red ghost spawn " +
+				"(first at line 2, column 3)",
+		},
+		{
+			name: "wrong column count",
+			data: shortLine,
+			err:  "line 5: 27 columns, expected 28 (the same as line 1)",
+		},
+		{
+			name: "missing spawn",
+			data: editDefaultMaze(24, 14, mazeEmpty),
+			err:  "missing Pacman spawn ('P')",
+		},
+		{
+			name: "unpaired portal",
+			data: editDefaultMaze(15, 1, mazePortal),
+			err: "line 15, column 1: warp portal without a pair " +
+				"(expected one at line 15, column 28)",
+		},
+		{
+			name: "empty maze",
+			data: []byte("\n\n"),
+			err:  "empty maze",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseMaze(test.data)
+			if err == nil {
+				t.Fatalf("expected error %q, got none", test.err)
+			}
+			if err.Error() != test.err {
+				t.Fatalf("expected error %q, got %q", test.err, err)
+			}
+		})
+	}
+
+	// The default maze itself should parse
+	if _, err := parseMaze(defaultMazeFile); err != nil {
+		t.Fatalf("default maze: %v", err)
+	}
+}
+
+// Check that the maze reported to clients matches the configured maze file
+func TestCurrentMaze(t *testing.T) {
+
+	// Configure a maze other than the default
+	prevMaze := currMaze
+	t.Cleanup(func() { currMaze = prevMaze })
+	if err := ConfigMazeFile("mazes/tunnels.txt"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile("mazes/tunnels.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The text should be the rows of the file, with walls where it has them
+	info := CurrentMaze()
+	text := strings.Join(info.Text, "\n")
+	if text != strings.TrimRight(string(data), "\r\n") {
+		t.Fatal("reported maze text differs from the maze file")
+	}
+	for row, line := range info.Text {
+		for col := range line {
+			wall := getBit(info.Walls[row], int8(col))
+			switch line[col] {
+			case mazeWall:
+				if !wall {
+					t.Fatalf("row %d, column %d: wall not reported", row, col)
+				}
+			case mazeEmpty, mazePellet:
+				if wall {
+					t.Fatalf("row %d, column %d: wall reported", row, col)
+				}
+			}
+		}
+	}
+
+	// Red's spawn should be reported where the maze file has it
+	spawn := info.GhostSpawns[red]
+	if info.Text[spawn[0]][spawn[1]] != mazeGhostSpawn {
+		t.Fatalf("reported red spawn (%d, %d) is not in the maze file",
+			spawn[0], spawn[1])
+	}
+}
+
+/*
+Check that Pacman (moved by commands or by tracking) and the ghosts go through
+the warp portals at the ends of row 14 of the arcade maze
+*/
+func TestWarpPortals(t *testing.T) {
+
+	// Play on the arcade maze, unpaused
+	prevMaze := currMaze
+	t.Cleanup(func() { currMaze = prevMaze })
+	if err := ConfigMazeFile("mazes/arcade.txt"); err != nil {
+		t.Fatal(err)
+	}
+	gs := newGameState(1)
+	gs.setMode(chase)
+
+	// Check a location's coordinates (and direction)
+	checkLoc := func(what string, loc *locationState, row, col int8, dir uint8) {
+		t.Helper()
+		if r, c := loc.getCoords(); r != row || c != col || loc.getDir() != dir {
+			t.Fatalf("%s at (%d, %d) facing %s, expected (%d, %d) facing %s",
+				what, r, c, dirNames[loc.getDir()], row, col, dirNames[dir])
+		}
+	}
+
+	// Only the coordinates just past a portal should wrap around
+	wraps := []struct{ row, col, wrapRow, wrapCol int8 }{
+		{14, -1, 14, 27},
+		{14, 28, 14, 0},
+		{13, -1, 13, -1},
+		{-1, 14, -1, 14},
+	}
+	for _, w := range wraps {
+		if row, col := gs.wrapCoords(w.row, w.col); row != w.wrapRow ||
+			col != w.wrapCol {
+			t.Fatalf("(%d, %d) wraps to (%d, %d), expected (%d, %d)",
+				w.row, w.col, row, col, w.wrapRow, w.wrapCol)
+		}
+	}
+
+	// Pacman should go through the portals both ways
+	gs.pacmanLoc.updateCoords(14, 0)
+	gs.movePacmanDir(left)
+	checkLoc("Pacman", gs.pacmanLoc, 14, 27, left)
+	gs.pacmanMoveTick = 0
+	gs.movePacmanDir(right)
+	checkLoc("Pacman", gs.pacmanLoc, 14, 0, right)
+
+	// Tracking should take the short way round, through the portal
+	gs.pacmanLoc.updateCoords(14, 1)
+	path := gs.findLikelyPath(14, 26)
+	expected := []pos{{14, 0}, {14, 27}, {14, 26}}
+	if !slices.Equal(path, expected) {
+		t.Fatalf("tracking path %v, expected %v", path, expected)
+	}
+	gs.movePacmanAbsolute(14, 26)
+	checkLoc("Pacman", gs.pacmanLoc, 14, 26, left)
+
+	// Red, heading left, should come out of the portal on the right
+	ghost := gs.ghosts[red]
+	ghost.setTrappedSteps(0)
+	ghost.setSpawning(false)
+	ghost.loc.updateCoords(14, 2)
+	ghost.loc.updateDir(left)
+	for _, col := range []int8{1, 0, 27, 26} {
+		ghost.plan()
+		ghost.update()
+		checkLoc("red", ghost.loc, 14, col, left)
+	}
+}
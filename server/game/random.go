@@ -0,0 +1,28 @@
+package game
+
+/*
+A small random number generator (SplitMix64), kept as a plain value so that
+its state is copied along with a cloned game state
+*/
+type randState struct {
+	state uint64
+}
+
+// Create a new random number generator from a seed
+func newRandState(seed int64) randState {
+	return randState{state: uint64(seed)}
+}
+
+// Generate the next pseudo-random 64-bit number
+func (r *randState) uint64() uint64 {
+	r.state += 0x9e3779b97f4a7c15
+	z := r.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Generate a pseudo-random number in the range [0, n) - n must be positive
+func (r *randState) intn(n int) int {
+	return int(r.uint64() % uint64(n))
+}
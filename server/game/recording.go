@@ -0,0 +1,201 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The version of the match recording format
+const recordingVersion int = 1
+
+// Enum-like declaration to hold the kinds of match records
+const (
+	recordHeader  string = "header"  // Written once, when recording starts
+	recordCommand string = "command" // Written for each interpreted command
+	recordFrame   string = "frame"   // Written for each new tick (optional)
+	recordEnd     string = "end"     // Written once, when recording stops
+)
+
+/*
+A single line (JSON object) of a match recording - the header records the
+config and seed in force (along with the maze and the names of the ghosts'
+strategies, since their files and code can change), and each command records
+the tick it was applied at (resets also record the seed of the new game). If
+enabled, the first serialized frame of each tick is recorded as well, to
+verify replays
+*/
+type matchRecord struct {
+	Kind       string          `json:"kind"`
+	Version    int             `json:"version,omitempty"`
+	Time       string          `json:"time,omitempty"`
+	Config     json.RawMessage `json:"config,omitempty"`
+	Maze       []string        `json:"maze,omitempty"`
+	Strategies []string        `json:"strategies,omitempty"`
+	Seed       *int64          `json:"seed,omitempty"`
+	Tick       uint32          `json:"tick"`
+	Cmd        string          `json:"cmd,omitempty"`
+	Args       []int           `json:"args,omitempty"`
+	Frame      []byte          `json:"frame,omitempty"`
+}
+
+/*
+A match recorder object, to write every command that the game engine
+interprets to a file, so that the match can be reproduced exactly
+*/
+type matchRecorder struct {
+	file          *os.File
+	encoder       *json.Encoder
+	recordFrames  bool   // whether to record frames as well as commands
+	lastFrameTick uint32 // the tick of the last recorded frame
+	frameDue      bool   // whether the next frame should be recorded
+}
+
+/*
+Create a new match recorder, writing to a new file in the given directory,
+and record a header with the given config, maze and seed (and the current
+ghost strategies)
+*/
+func newMatchRecorder(dir string, config any, maze *mazeLayout, seed int64,
+	recordFrames bool) (*matchRecorder, error) {
+
+	// Make sure the directory exists
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	// Name the file after the current time
+	now := time.Now()
+	name := fmt.Sprintf("match-%s.jsonl", now.Format("20060102-150405"))
+	file, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+
+	// Encode the config in force
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	// Write the header
+	mr := matchRecorder{
+		file:         file,
+		encoder:      json.NewEncoder(file),
+		recordFrames: recordFrames,
+		frameDue:     true,
+	}
+	err = mr.write(matchRecord{
+		Kind:       recordHeader,
+		Version:    recordingVersion,
+		Time:       now.Format(time.RFC3339),
+		Config:     configJSON,
+		Maze:       maze.text,
+		Strategies: ghostStrategyNames[:],
+		Seed:       &seed,
+	})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	// Log the location of the recording to the terminal
+	log.Printf("\033[35mLOG:  Recording match to %s\033[0m\n", file.Name())
+
+	// Return the match recorder
+	return &mr, nil
+}
+
+// Write a record to the match file
+func (mr *matchRecorder) write(record matchRecord) error {
+	return mr.encoder.Encode(record)
+}
+
+/*
+Record a command, along with the tick it was applied at (and, if the
+command reset the game, the seed of the new game)
+*/
+func (mr *matchRecorder) recordCommand(tick uint32, msg []byte,
+	rst bool, seed int64) {
+
+	// Split the command into its type and its arguments
+	record := matchRecord{
+		Kind: recordCommand,
+		Tick: tick,
+		Cmd:  string(msg[:1]),
+	}
+	for _, arg := range msg[1:] {
+		record.Args = append(record.Args, int(arg))
+	}
+
+	// Record the seed (and the next frame), if the game was reset
+	if rst {
+		record.Seed = &seed
+		mr.frameDue = true
+	}
+
+	// Write the record, logging any errors
+	if err := mr.write(record); err != nil {
+		log.Printf("\033[35mWARN: Failed to record command (%s)\033[0m\n", err)
+	}
+}
+
+/*
+Record a serialized frame, if frames are being recorded and it is the first
+frame of a new tick (frames while paused add nothing new)
+*/
+func (mr *matchRecorder) recordFrame(tick uint32, frame []byte) {
+
+	// Skip the frame if it isn't needed
+	if !mr.recordFrames || (tick == mr.lastFrameTick && !mr.frameDue) {
+		return
+	}
+	mr.lastFrameTick = tick
+	mr.frameDue = false
+
+	// Write the record, logging any errors
+	err := mr.write(matchRecord{
+		Kind:  recordFrame,
+		Tick:  tick,
+		Frame: frame,
+	})
+	if err != nil {
+		log.Printf("\033[35mWARN: Failed to record frame (%s)\033[0m\n", err)
+	}
+}
+
+// Record the tick that the match ended at, then close the match file
+func (mr *matchRecorder) close(tick uint32) {
+
+	// Write the end record, logging any errors
+	if err := mr.write(matchRecord{Kind: recordEnd, Tick: tick}); err != nil {
+		log.Printf("\033[35mWARN: Failed to record match end (%s)\033[0m\n",
+			err)
+	}
+
+	// Close the match file
+	if err := mr.file.Close(); err != nil {
+		log.Printf("\033[35mWARN: Failed to close match recording (%s)\033[0m\n",
+			err)
+	}
+}
+
+/*
+Start recording the match to a new file in the given directory, along with
+the config in force (and optionally, every new frame) - should be called
+before the game engine loop starts
+*/
+func (ge *GameEngine) StartRecording(dir string, config any,
+	recordFrames bool) error {
+	mr, err := newMatchRecorder(dir, config, ge.state.maze, ge.state.getSeed(),
+		recordFrames)
+	if err != nil {
+		return err
+	}
+	ge.recorder = mr
+	return nil
+}
@@ -0,0 +1,108 @@
+package game
+
+import "testing"
+
+// Check which ghosts are still held in the ghost house
+func checkHeld(t *testing.T, gs *gameState, pellets int, held [numColors]bool) {
+	t.Helper()
+	for color, ghost := range gs.ghosts {
+		if ghost.isHeld() != held[color] {
+			t.Fatalf("after %d pellets, %s ghost held = %t, expected %t",
+				pellets, ghostNames[color], ghost.isHeld(), held[color])
+		}
+	}
+}
+
+/*
+Check that with PelletRelease, each ghost leaves the ghost house once the
+configured number of pellets is eaten (by its own counter, then by the global
+counter after Pacman dies)
+*/
+func TestPelletRelease(t *testing.T) {
+
+	// Release the ghosts after pellets only, with no release timer
+	configTestRules(t, func(rules *Rules) {
+		rules.PelletRelease = true
+		rules.GlobalReleasePellets = [numColors]uint8{0, 4, 9, 12}
+		rules.Levels[0].ReleasePellets = [numColors]uint8{0, 3, 5, 2}
+		rules.Levels[0].ReleaseSteps = 0
+	})
+
+	// Each ghost counts pellets once it is next to leave
+	gs := newGameState(1)
+	expected := map[int][numColors]bool{
+		0:  {false, true, true, true},
+		2:  {false, true, true, true},
+		3:  {false, false, true, true},
+		7:  {false, false, true, true},
+		8:  {false, false, false, true},
+		9:  {false, false, false, true},
+		10: {false, false, false, false},
+	}
+	for pellets := 0; pellets <= 10; pellets++ {
+		if pellets > 0 {
+			gs.countReleasePellet()
+		}
+		if held, ok := expected[pellets]; ok {
+			checkHeld(t, gs, pellets, held)
+		}
+	}
+
+	/*
+		After Pacman dies, the global counter releases each ghost at its limit,
+		until it reaches orange's limit
+	*/
+	gs.deathReset()
+	if !gs.globalCounterActive {
+		t.Fatal("the global counter is not active after Pacman died")
+	}
+	expected = map[int][numColors]bool{
+		0:  {false, true, true, true},
+		3:  {false, true, true, true},
+		4:  {false, false, true, true},
+		8:  {false, false, true, true},
+		9:  {false, false, false, true},
+		11: {false, false, false, true},
+	}
+	for pellets := 0; pellets <= 11; pellets++ {
+		if pellets > 0 {
+			gs.countReleasePellet()
+		}
+		if held, ok := expected[pellets]; ok {
+			checkHeld(t, gs, pellets, held)
+		}
+	}
+	gs.countReleasePellet()
+	if gs.globalCounterActive {
+		t.Fatal("the global counter is still active at orange's limit")
+	}
+
+	// Orange then goes by its own counter, which already reached its limit
+	checkHeld(t, gs, 12, [numColors]bool{false, false, false, false})
+}
+
+// Check that the release timer lets out the next ghost if no pellets are eaten
+func TestReleaseTimer(t *testing.T) {
+
+	// Hold the ghosts for many pellets, but only a few steps
+	configTestRules(t, func(rules *Rules) {
+		rules.PelletRelease = true
+		rules.Levels[0].ReleasePellets = [numColors]uint8{0, 50, 50, 50}
+		rules.Levels[0].ReleaseSteps = 4
+	})
+
+	// Eating a pellet starts the timer over
+	gs := newGameState(1)
+	for step := 0; step < 3; step++ {
+		gs.stepReleaseTimer()
+	}
+	gs.countReleasePellet()
+	for step := 0; step < 3; step++ {
+		gs.stepReleaseTimer()
+	}
+	checkHeld(t, gs, 1, [numColors]bool{false, true, true, true})
+
+	// Once the timer runs out, the next ghost leaves
+	gs.stepReleaseTimer()
+	checkHeld(t, gs, 1, [numColors]bool{false, false, true, true})
+}
@@ -0,0 +1,458 @@
+package game
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+A match recording, read back from a file - the header holds the config and
+seed in force when the match started, followed by the rest of the records
+*/
+type Recording struct {
+	header  matchRecord
+	records []matchRecord
+}
+
+// Read a match recording from a file
+func ReadRecording(path string) (*Recording, error) {
+
+	// Open the file
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Decode each line into a record
+	rec := Recording{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		var record matchRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+
+		// The first record should be the header
+		if lineNum == 1 {
+			if record.Kind != recordHeader || record.Seed == nil {
+				return nil, fmt.Errorf("line 1: missing header")
+			}
+			if record.Version > recordingVersion {
+				return nil, fmt.Errorf("line 1: unsupported version %d",
+					record.Version)
+			}
+			rec.header = record
+			continue
+		}
+		rec.records = append(rec.records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Make sure there was a header at all
+	if rec.header.Kind != recordHeader {
+		return nil, fmt.Errorf("empty recording")
+	}
+
+	// Return the recording
+	return &rec, nil
+}
+
+// Get the (JSON-encoded) config that was in force during the match
+func (rec *Recording) Config() []byte {
+	return rec.header.Config
+}
+
+// Get the rows of the maze that the match was played on (nil if not recorded)
+func (rec *Recording) Maze() []string {
+	return rec.header.Maze
+}
+
+/*
+Configure the maze and ghost strategies that the match was played with, in
+place of the configured ones (in case the maze file or strategies have changed
+since) - recordings without them keep the configured ones
+*/
+func (rec *Recording) Configure() error {
+
+	// Play on the recorded maze
+	if rec.header.Maze != nil {
+		maze, err := parseMaze([]byte(strings.Join(rec.header.Maze, "\n")))
+		if err != nil {
+			return fmt.Errorf("recorded maze: %w", err)
+		}
+		currMaze = maze
+	}
+
+	/*
+		Assign the recorded strategies by name (strategies that were assigned
+		directly have no name, so they keep the configured ones)
+	*/
+	names := make(map[string]string)
+	for color, name := range rec.header.Strategies {
+		if color >= int(numColors) {
+			break
+		}
+		if name == "" {
+			log.Printf("\033[35mWARN: The recorded %s ghost strategy has no "+
+				"name, so the replay may differ\033[0m\n", ghostNames[color])
+			continue
+		}
+		names[ghostNames[color]] = name
+	}
+	if err := ConfigGhostStrategies(names); err != nil {
+		return fmt.Errorf("recorded strategies: %w", err)
+	}
+	return nil
+}
+
+/*
+Re-simulate a recording, returning the frames of the match in order (one per
+simulator step) - any recorded frames are checked against the re-simulated
+ones, and the number of mismatches is returned as well
+*/
+func (rec *Recording) simulate() ([][]byte, int, error) {
+
+	// Start the game the same way the recorded game engine did
+	sim := NewSimulator(*rec.header.Seed)
+	frames := [][]byte{sim.Snapshot()}
+	mismatches := 0
+
+	/*
+		Commands recorded at the same tick are applied together, in a single
+		step (any frames in between would have been paused, changing nothing)
+	*/
+	var pending [][]byte
+	var pendingTick uint32
+	flush := func() {
+		if len(pending) > 0 {
+			sim.Step(pending...)
+			frames = append(frames, sim.Snapshot())
+			pending = nil
+		}
+	}
+
+	// Step the simulator until it reaches a given tick
+	advance := func(tick uint32) error {
+		for sim.Ticks() != tick {
+			if sim.state.isPaused() {
+				return fmt.Errorf("desync: paused at tick %d, expected tick %d",
+					sim.Ticks(), tick)
+			}
+			sim.Step()
+			frames = append(frames, sim.Snapshot())
+		}
+		return nil
+	}
+
+	// Loop over each record
+	for idx, record := range rec.records {
+
+		// Prefix errors with the line number of the record
+		lineNum := idx + 2
+		var err error
+
+		switch record.Kind {
+
+		// Queue the command, after reaching the tick it was applied at
+		case recordCommand:
+			if len(pending) > 0 && record.Tick != pendingTick {
+				flush()
+			}
+			if len(pending) == 0 {
+				err = advance(record.Tick)
+				pendingTick = record.Tick
+			}
+			msg := []byte(record.Cmd)
+			for _, arg := range record.Args {
+				msg = append(msg, byte(arg))
+			}
+
+			/*
+				Resets record the seed of the new game, which was only picked
+				when the command was applied if none was given, so reset with
+				the recorded seed instead
+			*/
+			if record.Seed != nil {
+				msg = []byte(record.Cmd + strconv.FormatInt(*record.Seed, 10))
+			}
+			if len(msg) > 0 {
+				pending = append(pending, msg)
+			}
+
+		// Check the frame, once the tick it was recorded at is reached
+		case recordFrame:
+			flush()
+			err = advance(record.Tick)
+			if err == nil && !bytes.Equal(sim.Snapshot(), record.Frame) {
+				if mismatches < 10 {
+					log.Printf("\033[35mWARN: Replay frame mismatch "+
+						"(line %d, t = %d)\033[0m\n", lineNum, record.Tick)
+				}
+				mismatches++
+			}
+
+		// Play out the rest of the match
+		case recordEnd:
+			flush()
+			err = advance(record.Tick)
+		}
+
+		// If something went wrong, return what we have so far
+		if err != nil {
+			return frames, mismatches, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+	}
+
+	// Apply any leftover commands
+	flush()
+
+	// Return the frames
+	return frames, mismatches, nil
+}
+
+// The range of replay speeds (as multiples of the game's real-time speed)
+const (
+	minReplaySpeed float64 = 0.125
+	maxReplaySpeed float64 = 16
+)
+
+/*
+A replay engine object, to serve the re-simulated frames of a recorded match
+to clients (in place of a game engine), with play, pause, seek and speed
+controls coming from client commands
+*/
+type ReplayEngine struct {
+	quitCh      chan struct{}
+	webOutputCh chan<- []byte
+	webInputCh  <-chan []byte
+	ticker      *time.Ticker    // serves as the replay clock
+	wgQuit      *sync.WaitGroup // wait group to make sure it quits safely
+	frames      [][]byte        // re-simulated frames of the match
+	frameIdx    int             // index of the current frame
+	playing     bool            // whether the replay is playing
+	speed       float64         // frames advanced per clock tick
+	progress    float64         // fractional frames advanced so far
+}
+
+/*
+Create a new replay engine, re-simulating the recording (which should be done
+after the game is configured the same way as when it was recorded)
+*/
+func NewReplayEngine(rec *Recording, _webOutputCh chan<- []byte,
+	_webInputCh <-chan []byte, _wgQuit *sync.WaitGroup,
+	clockRate int32) *ReplayEngine {
+
+	// Re-simulate the match, and report how well it matched the recording
+	frames, mismatches, err := rec.simulate()
+	if err != nil {
+		log.Printf("\033[35m\033[1mERR:  Replay stopped early (%s)\033[0m\n", err)
+	}
+	if mismatches > 0 {
+		log.Printf("\033[35m\033[1mERR:  Replay does not match the "+
+			"recording (%d frame mismatches)\033[0m\n", mismatches)
+	} else {
+		log.Printf("\033[35mLOG:  Replay re-simulated (%d frames, all recorded "+
+			"frames match)\033[0m\n", len(frames))
+	}
+
+	// Time between ticks
+	_tickTime := 1000000 * time.Microsecond / time.Duration(clockRate)
+	re := ReplayEngine{
+		quitCh:      make(chan struct{}),
+		webOutputCh: _webOutputCh,
+		webInputCh:  _webInputCh,
+		ticker:      time.NewTicker(_tickTime),
+		wgQuit:      _wgQuit,
+		frames:      frames,
+		playing:     false,
+		speed:       1,
+	}
+
+	// Return the replay engine
+	return &re
+}
+
+// Quit by closing the replay engine, in case the loop ends
+func (re *ReplayEngine) quit() {
+
+	// Log that the replay engine successfully quit
+	log.Println("\033[35mLOG:  Replay engine successfully quit\033[0m")
+
+	// Decrement the quit wait group counter
+	re.wgQuit.Done()
+
+	// Free up the ticker
+	re.ticker.Stop()
+}
+
+// Quit function exported to other packages
+func (re *ReplayEngine) Quit() {
+	close(re.quitCh)
+}
+
+// Start the replay engine - should be launched as a go-routine
+func (re *ReplayEngine) RunLoop() {
+
+	// Quit if we ever run into an error or the program ends
+	defer re.quit()
+
+	// Increment the quit wait group counter
+	re.wgQuit.Add(1)
+
+	for {
+
+		// Send the current frame to the clients
+		re.webOutputCh <- re.currFrame()
+
+		// Read the input channel and update the replay accordingly
+	read_loop:
+		for {
+			select {
+			case msg := <-re.webInputCh:
+				re.interpretCommand(msg)
+			default:
+				break read_loop
+			}
+		}
+
+		// Move forward through the frames, if playing
+		if re.playing {
+			re.progress += re.speed
+			steps := int(re.progress)
+			re.progress -= float64(steps)
+			re.seek(re.frameIdx + steps)
+
+			// Pause once the end of the match is reached
+			if re.frameIdx == len(re.frames)-1 {
+				re.pause()
+			}
+		}
+
+		// Wait for the ticker to complete the current frame
+		select {
+		case <-re.ticker.C:
+		// If we get a quit signal, quit this engine
+		case <-re.quitCh:
+			return
+		}
+	}
+}
+
+/*
+Get a copy of the current frame - while the replay is paused, the game mode
+is shown as paused (so clients' play/pause controls stay in sync)
+*/
+func (re *ReplayEngine) currFrame() []byte {
+	frame := make([]byte, len(re.frames[re.frameIdx]))
+	copy(frame, re.frames[re.frameIdx])
+	if !re.playing {
+		frame[serGameModeIdx()] = paused
+	}
+	return frame
+}
+
+// Move to a given frame (clamped to the frames of the match)
+func (re *ReplayEngine) seek(frameIdx int) {
+	re.frameIdx = max(0, min(frameIdx, len(re.frames)-1))
+}
+
+// Pause the replay
+func (re *ReplayEngine) pause() {
+	if re.playing {
+		re.playing = false
+		log.Printf("\033[32m\033[2mREPL: Paused  (frame %d / %d)\033[0m\n",
+			re.frameIdx, len(re.frames)-1)
+	}
+}
+
+// Play the replay (from the start, if the end was reached)
+func (re *ReplayEngine) play() {
+	if !re.playing {
+		if re.frameIdx == len(re.frames)-1 {
+			re.seek(0)
+		}
+		re.playing = true
+		log.Printf("\033[32mREPL: Resumed (frame %d / %d)\033[0m\n",
+			re.frameIdx, len(re.frames)-1)
+	}
+}
+
+/*
+Convert byte messages from clients into replay controls:
+  - 'p' / 'P' pause and play
+  - 'r' / 'R' restart from the first frame
+  - 'k' seeks to a frame, followed by its (decimal) index
+  - 'j' jumps forward a number of frames, followed by a (decimal) count,
+    which may be negative
+  - 'f' sets the speed, followed by a (decimal) multiplier, which is clamped
+    between minReplaySpeed and maxReplaySpeed
+
+All other commands (such as Pacman movement) are ignored
+*/
+func (re *ReplayEngine) interpretCommand(msg []byte) {
+
+	switch msg[0] {
+
+	// Pause command
+	case 'p':
+		re.pause()
+
+	// Play command
+	case 'P':
+		re.play()
+
+	// Restart commands
+	case 'r', 'R':
+		re.pause()
+		re.seek(0)
+
+	// Seek command
+	case 'k':
+		frameIdx, err := strconv.Atoi(string(msg[1:]))
+		if err != nil {
+			logReplayArgError(msg)
+			return
+		}
+		re.seek(frameIdx)
+		re.progress = 0
+
+	// Jump command
+	case 'j':
+		frames, err := strconv.Atoi(string(msg[1:]))
+		if err != nil {
+			logReplayArgError(msg)
+			return
+		}
+		re.seek(re.frameIdx + frames)
+		re.progress = 0
+
+	// Speed command
+	case 'f':
+		speed, err := strconv.ParseFloat(string(msg[1:]), 64)
+		if err != nil || math.IsNaN(speed) || math.IsInf(speed, 0) {
+			logReplayArgError(msg)
+			return
+		}
+		re.speed = min(max(speed, minReplaySpeed), maxReplaySpeed)
+		log.Printf("\033[36mREPL: Speed changed (x%g)\033[0m\n", re.speed)
+	}
+}
+
+// Log an invalid argument to a replay control
+func logReplayArgError(msg []byte) {
+	log.Printf("\033[35m\033[1mERR:  Invalid replay argument %q "+
+		"(message type '%c'). Ignoring...\033[0m\n", msg[1:], msg[0])
+}
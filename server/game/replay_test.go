@@ -0,0 +1,171 @@
+package game
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+/*
+Record a match played on a headless game engine (including a reset without
+a seed), returning the recording read back from its file
+*/
+func recordMatch(t *testing.T) *Recording {
+	t.Helper()
+
+	// Start a headless engine, recording frames to a temporary directory
+	dir := t.TempDir()
+	inputCh := make(chan []byte, 10)
+	outputCh := make(chan []byte, 1)
+	ge := NewHeadlessGameEngine(outputCh, inputCh, &sync.WaitGroup{}, false)
+	if err := ge.StartRecording(dir, nil, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Play for a while, reset without a seed, then play some more
+	commands := map[int]string{
+		0:   "P",
+		100: "a",
+		250: "s",
+		300: "r",
+		301: "P",
+		400: "w",
+		500: "p",
+		510: "P",
+	}
+	for frame := 0; frame < 700; frame++ {
+		if msg, ok := commands[frame]; ok {
+			inputCh <- []byte(msg)
+		}
+		ge.runFrame()
+		<-outputCh
+	}
+	ge.recorder.close(ge.state.getCurrTicks())
+
+	// Read the recording back
+	paths, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if err != nil || len(paths) != 1 {
+		t.Fatalf("expected one recording, found %v (%v)", paths, err)
+	}
+	rec, err := ReadRecording(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+// Check that replaying a recording reproduces every recorded frame
+func TestRecordReplayRoundTrip(t *testing.T) {
+
+	// Replay a recorded match, checking every recorded frame
+	rec := recordMatch(t)
+	frames, mismatches, err := rec.simulate()
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if mismatches != 0 {
+		t.Fatalf("replay has %d frame mismatches", mismatches)
+	}
+	if len(frames) < 600 {
+		t.Fatalf("replay has %d frames, expected at least 600", len(frames))
+	}
+}
+
+/*
+Check that a recording keeps the maze and ghost strategies it was played with,
+so that it replays the same after they are configured differently
+*/
+func TestReplayConfiguresRecordedMaze(t *testing.T) {
+
+	// Record a match on a maze missing a pellet, with every ghost chasing like red
+	prevMaze := currMaze
+	prevStrategies, prevNames := ghostStrategies, ghostStrategyNames
+	t.Cleanup(func() {
+		currMaze = prevMaze
+		ghostStrategies, ghostStrategyNames = prevStrategies, prevNames
+	})
+	mazePath := filepath.Join(t.TempDir(), "maze.txt")
+	err := os.WriteFile(mazePath, editDefaultMaze(2, 2, mazeEmpty), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ConfigMazeFile(mazePath); err != nil {
+		t.Fatal(err)
+	}
+	err = ConfigGhostStrategies(map[string]string{
+		"pink": "red", "cyan": "red", "orange": "red",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordedMaze := currMaze
+	rec := recordMatch(t)
+
+	// The header should hold the maze and the strategy names
+	if !reflect.DeepEqual(rec.Maze(), recordedMaze.text) {
+		t.Fatal("the recorded maze differs from the maze played on")
+	}
+	expected := []string{"red", "red", "red", "red"}
+	if !reflect.DeepEqual(rec.header.Strategies, expected) {
+		t.Fatalf("recorded strategies %v, expected %v",
+			rec.header.Strategies, expected)
+	}
+
+	// Go back to the default maze and strategies, then replay the recording
+	currMaze = prevMaze
+	ghostStrategies, ghostStrategyNames = prevStrategies, prevNames
+	if err := rec.Configure(); err != nil {
+		t.Fatal(err)
+	}
+	if ghostStrategyNames != [numColors]string(expected) {
+		t.Fatalf("replay strategies %v, expected %v", ghostStrategyNames, expected)
+	}
+	_, mismatches, err := rec.simulate()
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if mismatches != 0 {
+		t.Fatalf("replay has %d frame mismatches", mismatches)
+	}
+}
+
+// Check that replay controls parse their arguments strictly
+func TestReplayControlArgs(t *testing.T) {
+
+	// A replay engine with some (empty) frames, at frame 10
+	re := &ReplayEngine{frames: make([][]byte, 100), speed: 1}
+	re.seek(10)
+
+	// Each control, with the frame and speed expected afterwards
+	controls := []struct {
+		msg      string
+		frameIdx int
+		speed    float64
+	}{
+		{"k20", 20, 1},
+		{"k2.5", 20, 1},  // not an integer
+		{"k1e1", 20, 1},  // not an integer
+		{"kabc", 20, 1},  // not a number
+		{"j-5", 15, 1},   // jumps can go backward
+		{"j+5", 20, 1},   // or forward
+		{"j0x10", 20, 1}, // not decimal
+		{"k1000", 99, 1}, // seeks are clamped to the recording
+		{"f2", 99, 2},
+		{"fNaN", 99, 2},
+		{"fInf", 99, 2},
+		{"f-Inf", 99, 2},
+		{"f100", 99, 16}, // speeds are clamped between 0.125 and 16
+		{"f0.01", 99, 0.125},
+		{"f0", 99, 0.125},
+		{"f-3", 99, 0.125},
+	}
+	for _, c := range controls {
+		re.interpretCommand([]byte(c.msg))
+		if re.frameIdx != c.frameIdx || re.speed != c.speed {
+			t.Fatalf("after %q: frame %d at x%g, expected frame %d at x%g",
+				c.msg, re.frameIdx, re.speed, c.frameIdx, c.speed)
+		}
+	}
+}
@@ -0,0 +1,517 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+/*
+A rules profile object, to hold the game constants that can be configured
+at startup (from a named profile in a rules file) - it isn't modified once
+loaded, so game states can share it
+*/
+type Rules struct {
+
+	// The name of the profile that these rules were loaded from
+	Name string
+
+	// The number of lives that Pacman starts with
+	InitLives uint8
+
+	// The points earned when collecting a pellet
+	PelletPoints uint16
+
+	// The points earned when collecting a super pellet
+	SuperPelletPoints uint16
+
+	// The multiplier for the combo from catching successive frightened ghosts
+	ComboMultiplier uint16
+
+	// The numbers of pellets at which to make the ghosts angry, then angrier
+	AngerThreshold1 uint16
+	AngerThreshold2 uint16
+
+	// The number of steps (update periods) that pass before the level speeds up
+	LevelDuration uint16
+
+	// The number of steps (update periods) before a level speeds up further
+	LevelPenaltyDuration uint16
+
+	/*
+		The score at which Pacman earns a bonus life (0 for no bonus lives),
+		and the points between each bonus life after that (0 for just one) -
+		bonus lives stop once Pacman has the maximum number of lives
+	*/
+	BonusLifeScore    uint32
+	BonusLifeInterval uint32
+	MaxLives          uint8
+
+	/*
+		Whether ghosts wait in the ghost house until pellet counters release
+		them (as in the arcade game), rather than for fixed numbers of steps
+	*/
+	PelletRelease bool
+
+	/*
+		The numbers of pellets (counted after Pacman dies) at which the global
+		pellet counter releases each ghost - once it reaches orange's, it stops
+		(without releasing orange), and the ghosts' own counters take over
+	*/
+	GlobalReleasePellets [numColors]uint8
+
+	/*
+		Whether to reproduce the arcade game's overflow bug in pink's and cyan's
+		targeting - when Pacman faces up, the spaces ahead of Pacman that they
+		aim at are also shifted the same number of spaces to the left
+	*/
+	UpOverflowBug bool
+
+	/*
+		Whether the ghosts getting angry (see the anger thresholds) follows the
+		arcade game's Cruise Elroy behavior - rather than the whole game
+		speeding up and the ghosts chasing, only red speeds up (in two stages,
+		each dropping the ticks between its moves by ElroySpeedup), and red
+		keeps chasing in scatter mode
+	*/
+	CruiseElroy  bool
+	ElroySpeedup uint8
+
+	/*
+		The speed of frightened ghosts, as a percentage of the speed of other
+		ghosts (100 for no slowdown)
+	*/
+	FrightSpeed uint8
+
+	/*
+		The speed of ghosts in tunnels, as a percentage of the speed of other
+		ghosts (100 for no slowdown, or about 50 as in the arcade game)
+	*/
+	GhostTunnelSpeed uint8
+
+	/*
+		Whether eaten ghosts return to the ghost house as eyes (as in the arcade
+		game), rather than reappearing there straight away, and the speed of the
+		eyes, as a percentage of the speed of other ghosts
+	*/
+	GhostEyes bool
+	EyesSpeed uint16
+
+	/*
+		The rules that change from level to level, starting from level 1
+		(levels past the end of the table use its last entry)
+	*/
+	Levels []LevelRules
+
+	// The fruit that can spawn, with their type ids given by their positions
+	Fruits []FruitRules
+}
+
+/*
+A level rules object, to hold the game constants that change from level to
+level - these are applied when the level starts
+*/
+type LevelRules struct {
+
+	// The update period (ghost speed) that the level starts with
+	UpdatePeriod uint8
+
+	/*
+		The minimum number of ticks between Pacman's moves (Pacman speed),
+		or 0 for no limit - moves that come sooner are ignored
+	*/
+	PacmanMovePeriod uint8
+
+	// The number of steps that the ghosts stay in the frightened state for
+	GhostFrightSteps uint8
+
+	/*
+		The lengths of the waves of the level's mode schedule, in units of
+		steps (update periods) - waves alternate between scatter and chase,
+		starting with scatter. Unless the schedule repeats (in which case it
+		should have an even number of waves), it should have an odd number of
+		waves, and the chase wave after the last one lasts forever
+	*/
+	Waves       []uint8
+	RepeatWaves bool
+
+	// The type of fruit that spawns (its position in the fruit roster)
+	FruitType uint8
+
+	/*
+		With PelletRelease, the numbers of pellets that each ghost waits in the
+		ghost house for (counted while it is the next ghost to leave), and the
+		number of steps without Pacman eating a pellet after which the next
+		ghost leaves anyway (0 for no limit)
+	*/
+	ReleasePellets [numColors]uint8
+	ReleaseSteps   uint8
+}
+
+/*
+A fruit rules object, to hold the features of a type of fruit in the fruit
+roster - the level rules pick which one spawns
+*/
+type FruitRules struct {
+
+	// The name of the fruit (for logging)
+	Name string
+
+	// The points earned upon collecting the fruit
+	Points uint16
+
+	// The number of steps that the fruit stays on the maze for
+	Duration uint8
+
+	// The numbers of pellets left at which the fruit spawns
+	Thresholds []uint16
+
+	// The location (row, column) that the fruit spawns at, if not the maze's
+	Spawn *[2]int8 `json:",omitempty"`
+}
+
+// The built-in rules, used unless a rules profile is configured
+var defaultRules = Rules{
+	Name:                 "default",
+	InitLives:            3,
+	PelletPoints:         10,
+	SuperPelletPoints:    50,
+	ComboMultiplier:      200,
+	AngerThreshold1:      20,
+	AngerThreshold2:      10,
+	LevelDuration:        960, // 8 minutes at 24 fps, update period = 12
+	LevelPenaltyDuration: 240, // 2 min (24fps, update period = 12)
+	BonusLifeScore:       0,   // no bonus lives
+	BonusLifeInterval:    0,
+	MaxLives:             5,
+	PelletRelease:        false, // fixed trapped steps
+	GlobalReleasePellets: [numColors]uint8{0, 7, 17, 32},
+	UpOverflowBug:        false, // textbook targeting
+	CruiseElroy:          false, // whole game speeds up
+	ElroySpeedup:         1,
+	FrightSpeed:          100,   // no slowdown
+	GhostTunnelSpeed:     100,   // no slowdown
+	GhostEyes:            false, // eaten ghosts reappear in the ghost house
+	EyesSpeed:            200,
+
+	// Each level speeds up the ghosts, but otherwise plays the same
+	Levels: []LevelRules{
+		defaultLevelRules(12), // level 1
+		defaultLevelRules(10), // level 2
+		defaultLevelRules(8),  // level 3
+		defaultLevelRules(6),  // level 4
+		defaultLevelRules(4),  // level 5
+		defaultLevelRules(2),  // level 6
+		defaultLevelRules(1),  // level 7 and up
+	},
+
+	// The arcade's fruit, all spawning in the same places as each other
+	Fruits: []FruitRules{
+		defaultFruitRules("cherry", 100),
+		defaultFruitRules("strawberry", 300),
+		defaultFruitRules("orange", 500),
+		defaultFruitRules("apple", 700),
+		defaultFruitRules("melon", 1000),
+		defaultFruitRules("galaxian", 2000),
+		defaultFruitRules("bell", 3000),
+		defaultFruitRules("key", 5000),
+	},
+}
+
+// The built-in rules for a level, given its update period
+func defaultLevelRules(updatePeriod uint8) LevelRules {
+	return LevelRules{
+		UpdatePeriod:     updatePeriod,
+		PacmanMovePeriod: 0,
+		GhostFrightSteps: 40,
+		Waves: []uint8{
+			60,  // scatter - 30 seconds at 24 fps, update period = 12
+			180, // chase   - 90 seconds at 24 fps, update period = 12
+		},
+		RepeatWaves: true,
+		FruitType:   0, // cherry
+	}
+}
+
+// The built-in rules for a type of fruit, given its name and points
+func defaultFruitRules(name string, points uint16) FruitRules {
+	return FruitRules{
+		Name:       name,
+		Points:     points,
+		Duration:   30,
+		Thresholds: []uint16{174, 74},
+	}
+}
+
+// The rules that new games are played with
+var currRules *Rules = &defaultRules
+
+/*
+Configure the rules that new games are played with, returning an error
+(without changing them) if they are invalid - they are checked against the
+current maze, so the maze should be configured first
+*/
+func ConfigRules(rules *Rules) error {
+	if err := rules.validate(); err != nil {
+		return err
+	}
+
+	// Make sure that no fruit spawns in a wall
+	for idx, fruit := range rules.Fruits {
+		if fruit.Spawn != nil && getBit(currMaze.walls[fruit.Spawn[0]], fruit.Spawn[1]) {
+			return fmt.Errorf("Fruits[%d]: Spawn (%d, %d) is in a wall of the maze",
+				idx, fruit.Spawn[0], fruit.Spawn[1])
+		}
+	}
+
+	currRules = rules
+	return nil
+}
+
+/*
+Load a named rules profile from a rules file - the file holds an object
+mapping profile names to rules, and any rules left out of a profile keep
+their built-in values
+*/
+func LoadRules(path string, name string) (*Rules, error) {
+
+	// Read the rules file
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Split the file into its profiles
+	var profiles map[string]json.RawMessage
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	profile, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: no rules profile named %q", path, name)
+	}
+
+	/*
+		Decode the profile on top of the built-in rules, rejecting unknown keys
+		(a level table or fruit roster replaces the built-in one as a whole,
+		rather than being decoded into it)
+	*/
+	rules := defaultRules
+	rules.Levels = nil
+	rules.Fruits = nil
+	decoder := json.NewDecoder(bytes.NewReader(profile))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("%s: profile %q: %w", path, name, err)
+	}
+	if rules.Levels == nil {
+		rules.Levels = defaultRules.Levels
+	}
+	if rules.Fruits == nil {
+		rules.Fruits = defaultRules.Fruits
+	}
+	rules.Name = name
+
+	// Make sure the rules make sense
+	if err := rules.validate(); err != nil {
+		return nil, fmt.Errorf("%s: profile %q: %w", path, name, err)
+	}
+	return &rules, nil
+}
+
+// Get the rules that new games are played with (not to be modified)
+func CurrentRules() *Rules {
+	return currRules
+}
+
+// Check that a set of rules can be played with
+func (rules *Rules) validate() error {
+
+	// Durations and periods of zero would never count down
+	nonZero := []struct {
+		name  string
+		value uint16
+	}{
+		{"InitLives", uint16(rules.InitLives)},
+		{"LevelDuration", rules.LevelDuration},
+		{"LevelPenaltyDuration", rules.LevelPenaltyDuration},
+	}
+	for _, field := range nonZero {
+		if field.value == 0 {
+			return fmt.Errorf("%s must be at least 1", field.name)
+		}
+	}
+
+	// Frightened ghosts should move, but not faster than the others
+	if rules.FrightSpeed == 0 || rules.FrightSpeed > 100 {
+		return fmt.Errorf("FrightSpeed (%d) must be between 1 and 100",
+			rules.FrightSpeed)
+	}
+
+	// Ghosts in tunnels should move, but not faster than the others
+	if rules.GhostTunnelSpeed == 0 || rules.GhostTunnelSpeed > 100 {
+		return fmt.Errorf("GhostTunnelSpeed (%d) must be between 1 and 100",
+			rules.GhostTunnelSpeed)
+	}
+
+	// Eyes should be at least as fast as the other ghosts
+	if rules.EyesSpeed < 100 {
+		return fmt.Errorf("EyesSpeed (%d) must be at least 100", rules.EyesSpeed)
+	}
+
+	// Pacman should be able to hold a bonus life, if there are any
+	if rules.BonusLifeScore != 0 && rules.MaxLives <= rules.InitLives {
+		return fmt.Errorf("MaxLives (%d) must be more than InitLives (%d) "+
+			"for bonus lives", rules.MaxLives, rules.InitLives)
+	}
+
+	// The ghosts should get angry before they get angrier
+	if rules.AngerThreshold2 >= rules.AngerThreshold1 {
+		return fmt.Errorf("AngerThreshold2 (%d) must be less than "+
+			"AngerThreshold1 (%d)", rules.AngerThreshold2, rules.AngerThreshold1)
+	}
+
+	// Every type of fruit needs to be valid, and have an id that fits a byte
+	if len(rules.Fruits) == 0 || len(rules.Fruits) > 256 {
+		return fmt.Errorf("Fruits must have between 1 and 256 entries")
+	}
+	for idx := range rules.Fruits {
+		if err := rules.Fruits[idx].validate(); err != nil {
+			return fmt.Errorf("Fruits[%d]: %w", idx, err)
+		}
+	}
+
+	// Every level needs rules, starting from level 1
+	if len(rules.Levels) == 0 {
+		return fmt.Errorf("Levels must have at least one entry")
+	}
+	for idx := range rules.Levels {
+		if err := rules.Levels[idx].validate(len(rules.Fruits)); err != nil {
+			return fmt.Errorf("Levels[%d] (level %d): %w", idx, idx+1, err)
+		}
+	}
+	return nil
+}
+
+// Check that the rules for a type of fruit can be played with
+func (fr *FruitRules) validate() error {
+
+	// The fruit needs a name to show up in logs, and a duration to count down
+	if fr.Name == "" {
+		return fmt.Errorf("Name must not be empty")
+	}
+	if fr.Duration == 0 {
+		return fmt.Errorf("Duration must be at least 1")
+	}
+
+	// The spawn location (if any) should be within the maze's bounds
+	if fr.Spawn != nil {
+		row, col := fr.Spawn[0], fr.Spawn[1]
+		if row < 0 || row >= mazeRows || col < 0 || col >= mazeCols {
+			return fmt.Errorf("Spawn (%d, %d) must be within the maze", row, col)
+		}
+	}
+	return nil
+}
+
+// Check that the rules for a level can be played with (given the roster size)
+func (lr *LevelRules) validate(numFruits int) error {
+
+	// Periods and durations of zero would never count down
+	if lr.UpdatePeriod == 0 {
+		return fmt.Errorf("UpdatePeriod must be at least 1")
+	}
+	for idx, duration := range lr.Waves {
+		if duration == 0 {
+			return fmt.Errorf("Waves[%d] must be at least 1", idx)
+		}
+	}
+
+	// Fright steps share their byte of each frame with the spawning flag
+	if lr.GhostFrightSteps >= 128 {
+		return fmt.Errorf("GhostFrightSteps (%d) must be less than 128",
+			lr.GhostFrightSteps)
+	}
+
+	/*
+		The waves should alternate properly, ending in a chase wave if the
+		schedule repeats (or before the last, endless chase wave otherwise)
+	*/
+	numWaves := len(lr.Waves)
+	switch {
+	case numWaves == 0 || numWaves > 254:
+		return fmt.Errorf("Waves must have between 1 and 254 entries")
+	case lr.RepeatWaves && numWaves%2 != 0:
+		return fmt.Errorf("Waves must have an even number of entries " +
+			"(ending in chase) if RepeatWaves is set")
+	case !lr.RepeatWaves && numWaves%2 == 0:
+		return fmt.Errorf("Waves must have an odd number of entries " +
+			"(ending in scatter, before chasing forever) unless RepeatWaves is set")
+	}
+
+	// The fruit type should be in the fruit roster
+	if int(lr.FruitType) >= numFruits {
+		return fmt.Errorf("FruitType (%d) must be less than %d (the number "+
+			"of Fruits)", lr.FruitType, numFruits)
+	}
+	return nil
+}
+
+// Get the rules for a level's fruit
+func (rules *Rules) fruit(lr *LevelRules) *FruitRules {
+	return &rules.Fruits[lr.FruitType]
+}
+
+// Get the rules for a level (levels past the end of the table use its end)
+func (rules *Rules) level(level uint8) *LevelRules {
+	idx := min(max(int(level), 1), len(rules.Levels)) - 1
+	return &rules.Levels[idx]
+}
+
+// The game mode of a wave (waves alternate, starting with scatter)
+func waveMode(waveIdx uint8) uint8 {
+	if waveIdx%2 == 0 {
+		return scatter
+	}
+	return chase
+}
+
+// Determines whether a wave is the last one, lasting forever
+func (lr *LevelRules) isFinalWave(waveIdx uint8) bool {
+	return !lr.RepeatWaves && int(waveIdx) >= len(lr.Waves)
+}
+
+/*
+The length of a wave, in units of steps (update periods) - the last wave
+(lasting forever) has the same length as paused mode
+*/
+func (lr *LevelRules) waveDuration(waveIdx uint8) uint8 {
+	if int(waveIdx) >= len(lr.Waves) {
+		return 255
+	}
+	return lr.Waves[waveIdx]
+}
+
+// The wave that follows a given wave
+func (lr *LevelRules) nextWave(waveIdx uint8) uint8 {
+
+	// The last wave lasts forever
+	if lr.isFinalWave(waveIdx) {
+		return waveIdx
+	}
+
+	// A repeating schedule starts over after its last wave
+	if lr.RepeatWaves && int(waveIdx)+1 >= len(lr.Waves) {
+		return 0
+	}
+	return waveIdx + 1
+}
+
+// The first chase wave, starting from a given wave
+func (lr *LevelRules) chaseWave(waveIdx uint8) uint8 {
+	if waveMode(waveIdx) == chase {
+		return waveIdx
+	}
+	return lr.nextWave(waveIdx)
+}
@@ -0,0 +1,122 @@
+package game
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Copy the built-in rules, with their own level table and fruit roster
+func copyDefaultRules() Rules {
+	rules := defaultRules
+	rules.Levels = append([]LevelRules(nil), defaultRules.Levels...)
+	rules.Fruits = append([]FruitRules(nil), defaultRules.Fruits...)
+	return rules
+}
+
+/*
+Configure a copy of the built-in rules, changed by a given function, for the
+rest of a test (the previous rules are restored afterwards)
+*/
+func configTestRules(t *testing.T, modify func(rules *Rules)) {
+	t.Helper()
+	prevRules := currRules
+	t.Cleanup(func() { currRules = prevRules })
+	rules := copyDefaultRules()
+	modify(&rules)
+	if err := ConfigRules(&rules); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Check that validating rules rejects values that can't be played with
+func TestRulesValidateRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(rules *Rules)
+		err    string
+	}{
+		{"zero lives", func(r *Rules) { r.InitLives = 0 },
+			"InitLives must be at least 1"},
+		{"still fright", func(r *Rules) { r.FrightSpeed = 0 },
+			"FrightSpeed (0) must be between 1 and 100"},
+		{"fast fright", func(r *Rules) { r.FrightSpeed = 101 },
+			"FrightSpeed (101) must be between 1 and 100"},
+		{"still tunnels", func(r *Rules) { r.GhostTunnelSpeed = 0 },
+			"GhostTunnelSpeed (0) must be between 1 and 100"},
+		{"fast tunnels", func(r *Rules) { r.GhostTunnelSpeed = 101 },
+			"GhostTunnelSpeed (101) must be between 1 and 100"},
+		{"slow eyes", func(r *Rules) { r.EyesSpeed = 99 },
+			"EyesSpeed (99) must be at least 100"},
+		{"anger order", func(r *Rules) { r.AngerThreshold2 = r.AngerThreshold1 },
+			"AngerThreshold2"},
+		{"no levels", func(r *Rules) { r.Levels = nil },
+			"Levels must have at least one entry"},
+		{"no fruits", func(r *Rules) { r.Fruits = nil },
+			"Fruits must have between 1 and 256 entries"},
+		{"unnamed fruit", func(r *Rules) { r.Fruits[0].Name = "" },
+			"Fruits[0]: Name must not be empty"},
+		{"fruit outside maze", func(r *Rules) { r.Fruits[0].Spawn = &[2]int8{0, 32} },
+			"Fruits[0]: Spawn (0, 32) must be within the maze"},
+		{"zero update period", func(r *Rules) { r.Levels[0].UpdatePeriod = 0 },
+			"Levels[0] (level 1): UpdatePeriod must be at least 1"},
+		{"wide fright steps", func(r *Rules) { r.Levels[0].GhostFrightSteps = 128 },
+			"Levels[0] (level 1): GhostFrightSteps (128) must be less than 128"},
+		{"zero wave", func(r *Rules) { r.Levels[0].Waves = []uint8{0} },
+			"Levels[0] (level 1): Waves[0] must be at least 1"},
+		{"no waves", func(r *Rules) { r.Levels[0].Waves = nil },
+			"Levels[0] (level 1): Waves must have between 1 and 254 entries"},
+		{"odd repeating waves", func(r *Rules) {
+			r.Levels[0].Waves = []uint8{1, 2, 3}
+			r.Levels[0].RepeatWaves = true
+		}, "Waves must have an even number of entries"},
+		{"even waves", func(r *Rules) {
+			r.Levels[0].Waves = []uint8{1, 2}
+			r.Levels[0].RepeatWaves = false
+		}, "Waves must have an odd number of entries"},
+		{"missing fruit type", func(r *Rules) {
+			r.Levels[0].FruitType = uint8(len(r.Fruits))
+		}, "FruitType"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rules := copyDefaultRules()
+			test.modify(&rules)
+			err := rules.validate()
+			if err == nil {
+				t.Fatalf("expected an error containing %q, got none", test.err)
+			}
+			if !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected an error containing %q, got %q", test.err, err)
+			}
+
+			// Invalid rules shouldn't be configured
+			prevRules := currRules
+			if ConfigRules(&rules) == nil || currRules != prevRules {
+				currRules = prevRules
+				t.Fatal("invalid rules were configured")
+			}
+		})
+	}
+}
+
+/*
+Check that every profile in the rules file loads, and that the official
+profile matches the built-in rules
+*/
+func TestRulesProfiles(t *testing.T) {
+	for _, name := range []string{"official", "practice-easy", "arcade"} {
+		rules, err := LoadRules("../../rules.json", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name == "official" {
+			expected := defaultRules
+			expected.Name = name
+			if !reflect.DeepEqual(*rules, expected) {
+				t.Fatal("the official profile differs from the built-in rules")
+			}
+		}
+	}
+}
@@ -5,13 +5,35 @@ IMPORTANT NOTE: All serializations are encoded in big-endian form
 (most significant byte, MSB, first)
 */
 
+// The size of a buffer large enough to hold a full serialized game state
+const serBufSize int = 512
+
+/*
+Whether to use the legacy serialization, with 16-bit ticks and score
+(for compatibility with older clients)
+*/
+var legacyProtocol bool = false
+
+// Configure whether to use the legacy serialization
+func ConfigLegacyProtocol(_legacyProtocol bool) {
+	legacyProtocol = _legacyProtocol
+}
+
+// The index of the game mode within a full serialized game state
+func serGameModeIdx() int {
+	if legacyProtocol {
+		return 3
+	}
+	return 5
+}
+
 /**************************** Integer Serialization ***************************/
 
 /*
 Get the byte at a particular index (0 = least significant byte,
 1 = second least, etc.)
 */
-func getByte[T uint8 | uint16 | uint32](num T, byteIdx int) byte {
+func getByte[T uint8 | uint16 | uint32 | uint64](num T, byteIdx int) byte {
 
 	/*
 		Uses bitwise operation magic (not really, look up how the >> and &
@@ -58,15 +80,28 @@ func serUint32(num uint32, outputBuf []byte, startIdx int) int {
 	return startIdx
 }
 
+// Serialize a uint64 (eight getByte calls)
+func serUint64(num uint64, outputBuf []byte, startIdx int) int {
+
+	// Loop over each of the 8 bytes within the row (MSB first)
+	for byteIdx := 7; byteIdx >= 0; byteIdx-- {
+
+		// Serialize the byte
+		outputBuf[startIdx] = getByte(num, byteIdx)
+
+		// Add 1 to the start index, to prepare for serializing the next byte
+		startIdx++
+	}
+
+	// Return the starting index of the next field
+	return startIdx
+}
+
 /***************************** Field Serialization ****************************/
 
 // Serialize a location (no getByte calls, serialized manually)
 func serLocation(loc *locationState, outputBuf []byte, startIdx int) int {
 
-	// (Read) lock the location state
-	loc.RLock()
-	defer loc.RUnlock()
-
 	// Cover each coordinate of the location, one at a time
 	outputBuf[startIdx+0] = byte((dRow[loc.dir] << 6) | loc.row)
 	outputBuf[startIdx+1] = byte((dCol[loc.dir] << 6) | loc.col)
@@ -75,11 +110,16 @@ func serLocation(loc *locationState, outputBuf []byte, startIdx int) int {
 	return startIdx + 2
 }
 
-// Serialize the current number of ticks (2 bytes)
+// Serialize the current number of ticks (4 bytes, or 2 if legacy)
 func (gs *gameState) serCurrTicks(outputBuf []byte, startIdx int) int {
 
+	// Legacy clients get the lower 16 bits (wrapping around, if needed)
+	if legacyProtocol {
+		return serUint16(uint16(gs.getCurrTicks()), outputBuf, startIdx)
+	}
+
 	// Serialize and return the starting index of the next field
-	return serUint16(gs.getCurrTicks(), outputBuf, startIdx)
+	return serUint32(gs.getCurrTicks(), outputBuf, startIdx)
 }
 
 // Serialize the update period (1 byte)
@@ -105,8 +145,8 @@ func (gs *gameState) serModeSteps(outputBuf []byte, startIdx int) int {
 	// Serialize the number of mode steps
 	startIdx = serUint8(gs.getModeSteps(), outputBuf, startIdx)
 
-	// Serialize the duration of this (last unpaused) mode
-	modeDuration := modeDurations[gs.getLastUnpausedMode()]
+	// Serialize the duration of this wave of the mode schedule
+	modeDuration := gs.levelRules.waveDuration(gs.getWave())
 	startIdx = serUint8(modeDuration, outputBuf, startIdx)
 
 	// Return the starting index of the next field
@@ -122,11 +162,17 @@ func (gs *gameState) serLevelSteps(outputBuf []byte, startIdx int) int {
 	return serUint16(gs.getLevelSteps(), outputBuf, startIdx)
 }
 
-// Serialize the current score (2 bytes)
+// Serialize the current score (4 bytes, or 2 if legacy)
 func (gs *gameState) serCurrScore(outputBuf []byte, startIdx int) int {
 
+	// Legacy clients get the score capped at the maximum 16-bit unsigned int
+	if legacyProtocol {
+		score := uint16(min(gs.getScore(), 65535))
+		return serUint16(score, outputBuf, startIdx)
+	}
+
 	// Serialize and return the starting index of the next field
-	return serUint16(gs.getScore(), outputBuf, startIdx)
+	return serUint32(gs.getScore(), outputBuf, startIdx)
 }
 
 // Serialize the current level (1 byte)
@@ -153,10 +199,6 @@ func (gs *gameState) serGhostCombo(outputBuf []byte, startIdx int) int {
 // Serialize the pellets (4 * mazeRows bytes)
 func (gs *gameState) serPellets(outputBuf []byte, startIdx int) int {
 
-	// (Read) lock the pellets array
-	gs.muPellets.RLock()
-	defer gs.muPellets.RUnlock()
-
 	// Loop over each row
 	for row := int8(0); row < mazeRows; row++ {
 
@@ -168,38 +210,56 @@ func (gs *gameState) serPellets(outputBuf []byte, startIdx int) int {
 	return startIdx
 }
 
+/*
+Serialize the locations of the maze's super pellets, collected or not, in the
+same form as the pellets (4 * mazeRows bytes) - a pellet is a super pellet if its bit
+is set in both
+*/
+func (gs *gameState) serSuperPellets(outputBuf []byte, startIdx int) int {
+
+	// Loop over each row
+	for row := int8(0); row < mazeRows; row++ {
+
+		// Serialize each row from uint32 to 4 bytes
+		startIdx = serUint32(gs.maze.superPellets[row], outputBuf, startIdx)
+	}
+
+	// Return the starting index of the next field
+	return startIdx
+}
+
 // Serialize the location of Pacman (2 bytes)
 func (gs *gameState) serPacman(outputBuf []byte, startIdx int) int {
 
-	// Serialize the pacman state (with locking to be thread-safe)
+	// Serialize the pacman state
 	startIdx = serLocation(gs.pacmanLoc, outputBuf, startIdx)
 
 	// Return the starting index of the next field
 	return startIdx
 }
 
-// Serialize the location of the fruit (2 bytes)
+// Serialize the location and info of the fruit (5 bytes, or 4 if legacy)
 func (gs *gameState) serFruit(outputBuf []byte, startIdx int) int {
 
-	// (Read) lock the fruit state
-	gs.muFruit.RLock()
-	{
-		if gs.fruitExists() { // Serialize the fruit's location if it exists
-			startIdx = serLocation(gs.fruitLoc, outputBuf, startIdx)
-		} else { // Otherwise, give an empty (0x00 0x00) location
-			startIdx = serLocation(emptyLoc, outputBuf, startIdx)
-		}
+	if gs.fruitExists() { // Serialize the fruit's location if it exists
+		startIdx = serLocation(gs.fruitLoc, outputBuf, startIdx)
+	} else { // Otherwise, give an empty (0x00 0x00) location
+		startIdx = serLocation(emptyLoc, outputBuf, startIdx)
 	}
-	gs.muFruit.RUnlock()
 
 	// Serialize the number of steps the fruit has been spawned
 	fruitSteps := gs.getFruitSteps()
 	startIdx = serUint8(fruitSteps, outputBuf, startIdx)
 
 	// Serialize the duration of the fruit
-	fruitDuration := fruitDuration
+	fruitDuration := gs.getFruit().Duration
 	startIdx = serUint8(fruitDuration, outputBuf, startIdx)
 
+	// Serialize the type of the fruit (not for legacy clients)
+	if !legacyProtocol {
+		startIdx = serUint8(gs.levelRules.FruitType, outputBuf, startIdx)
+	}
+
 	// Return the starting index of the next field
 	return startIdx
 }
@@ -213,10 +273,6 @@ func (gs *gameState) serGhost(color uint8, outputBuf []byte, startIdx int) int {
 	// Serialize the location information first
 	startIdx = serLocation(g.loc, outputBuf, startIdx)
 
-	// Lock the ghost's other state variables
-	g.muState.RLock()
-	defer g.muState.RUnlock()
-
 	// Add a flag at the 7th (highest) bit to indicate spawning
 	var spawnFlag uint8 = 0
 	if g.spawning {
@@ -232,8 +288,14 @@ func (gs *gameState) serGhost(color uint8, outputBuf []byte, startIdx int) int {
 		eatenFlag = 0b10000000
 	}
 
-	// Serialize the trapped steps and eaten flag info next
-	startIdx = serUint8(g.trappedSteps|eatenFlag, outputBuf, startIdx)
+	// Add a flag at the 6th bit to indicate eyes (returning to the ghost house)
+	var eyesFlag uint8 = 0
+	if g.eyes {
+		eyesFlag = 0b01000000
+	}
+
+	// Serialize the trapped steps, eaten and eyes flag info next
+	startIdx = serUint8(g.trappedSteps|eatenFlag|eyesFlag, outputBuf, startIdx)
 
 	// Return the starting index of the next field
 	return startIdx
@@ -252,6 +314,74 @@ func (gs *gameState) serGhosts(outputBuf []byte, startIdx int) int {
 	return startIdx
 }
 
+// Serialize the current wave of the mode schedule (1 byte)
+func (gs *gameState) serWave(outputBuf []byte, startIdx int) int {
+
+	// Serialize and return the starting index of the next field
+	return serUint8(gs.getWave(), outputBuf, startIdx)
+}
+
+/*
+Serialize the ghost release counters (7 bytes) - each ghost's pellet counter,
+the global pellet counter, the steps since Pacman last ate a pellet, then a
+byte of flags (the lowest 4 bits for the held ghosts, by color, and the
+highest bit for whether the global counter is in use)
+*/
+func (gs *gameState) serRelease(outputBuf []byte, startIdx int) int {
+
+	// Serialize each ghost's pellet counter, keeping track of the held ghosts
+	var flags uint8 = 0
+	for _, ghost := range gs.ghosts {
+		startIdx = serUint8(ghost.getPelletCounter(), outputBuf, startIdx)
+		modifyBit(&flags, ghost.color, ghost.isHeld())
+	}
+
+	// Add a flag at the 7th (highest) bit to indicate the global counter is used
+	if gs.globalCounterActive {
+		flags |= 0b10000000
+	}
+
+	// Serialize the global counter, release timer, and flags
+	startIdx = serUint8(gs.globalPelletCounter, outputBuf, startIdx)
+	startIdx = serUint8(gs.releaseSteps, outputBuf, startIdx)
+	return serUint8(flags, outputBuf, startIdx)
+}
+
+// Serialize the stage of red's Cruise Elroy speed-up, or 0 for none (1 byte)
+func (gs *gameState) serElroy(outputBuf []byte, startIdx int) int {
+
+	// Serialize and return the starting index of the next field
+	return serUint8(gs.elroyStage, outputBuf, startIdx)
+}
+
+/*
+Serialize the speeds of the agents (6 bytes) - the ticks between each ghost's
+moves (a ghost moves at ticks that are multiples of its period), then the
+minimum ticks between Pacman's moves, and the ticks until Pacman can move again
+*/
+func (gs *gameState) serSpeeds(outputBuf []byte, startIdx int) int {
+
+	// Serialize each ghost's move period
+	for _, ghost := range gs.ghosts {
+		startIdx = serUint8(ghost.getMovePeriod(), outputBuf, startIdx)
+	}
+
+	// Serialize Pacman's move period, and how long until it can move again
+	var pacmanWait uint32 = 0
+	if gs.pacmanMoveTick > gs.getCurrTicks() {
+		pacmanWait = min(gs.pacmanMoveTick-gs.getCurrTicks(), 255)
+	}
+	startIdx = serUint8(gs.levelRules.PacmanMovePeriod, outputBuf, startIdx)
+	return serUint8(uint8(pacmanWait), outputBuf, startIdx)
+}
+
+// Serialize the random seed of the game, two's complement (8 bytes)
+func (gs *gameState) serSeed(outputBuf []byte, startIdx int) int {
+
+	// Serialize and return the starting index of the next field
+	return serUint64(uint64(gs.getSeed()), outputBuf, startIdx)
+}
+
 /***************************** State Serialization ****************************/
 
 // Serialize all the information of the game state
@@ -282,6 +412,24 @@ func (gs *gameState) serFull(outputBuf []byte, startIdx int) int {
 	// Pellets - serializes the pellets to the buffer
 	startIdx = gs.serPellets(outputBuf, startIdx)
 
+	// Seed - serializes the random seed, so that the game can be replayed
+	startIdx = gs.serSeed(outputBuf, startIdx)
+
+	// Wave - serializes the wave of the mode schedule, to predict mode changes
+	startIdx = gs.serWave(outputBuf, startIdx)
+
+	// Super pellets - serializes where the maze's super pellets are
+	startIdx = gs.serSuperPellets(outputBuf, startIdx)
+
+	// Ghost release - serializes the pellet counters that release the ghosts
+	startIdx = gs.serRelease(outputBuf, startIdx)
+
+	// Cruise Elroy - serializes the stage of red's speed-up
+	startIdx = gs.serElroy(outputBuf, startIdx)
+
+	// Speeds - serializes how often each agent moves
+	startIdx = gs.serSpeeds(outputBuf, startIdx)
+
 	// Return the starting index of the next field
 	return startIdx
 }
@@ -0,0 +1,151 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+// Read a big-endian unsigned integer of a given number of bytes from a frame
+func readUint(frame []byte, startIdx int, numBytes int) uint64 {
+	var num uint64 = 0
+	for idx := startIdx; idx < startIdx+numBytes; idx++ {
+		num = (num << 8) | uint64(frame[idx])
+	}
+	return num
+}
+
+/*
+Check the byte offsets and lengths of the fields of a full serialized game
+state, with and without the legacy protocol (which has 16-bit ticks and score,
+and no fruit type), for ticks and a score that don't fit in 16 bits
+*/
+func TestSerFullLayout(t *testing.T) {
+
+	// Restore the protocol after the test
+	prevLegacyProtocol := legacyProtocol
+	t.Cleanup(func() { ConfigLegacyProtocol(prevLegacyProtocol) })
+
+	// A game state with distinctive values for the fields checked below
+	gs := newGameState(-2)
+	gs.currTicks = 0x12345
+	gs.currScore = 70000
+	gs.currLevel = 3
+	gs.currLives = 2
+	gs.waveIdx = 4
+	gs.elroyStage = 1
+
+	// Each field, with its offset and size (and its expected value, if known)
+	rows := int(mazeRows)
+	type field struct {
+		name       string
+		idx        int
+		legacyIdx  int
+		size       int
+		legacySize int
+		value      uint64
+		legacyVal  uint64
+		checked    bool
+	}
+	fields := []field{
+		{"ticks", 0, 0, 4, 2, 0x12345, 0x2345, true},
+		{"update period", 4, 2, 1, 1, 0, 0, false},
+		{"mode", 5, 3, 1, 1, uint64(paused), uint64(paused), true},
+		{"mode steps", 6, 4, 2, 2, 0, 0, false},
+		{"level steps", 8, 6, 2, 2, 0, 0, false},
+		{"score", 10, 8, 4, 2, 70000, 65535, true},
+		{"level", 14, 10, 1, 1, 3, 3, true},
+		{"lives", 15, 11, 1, 1, 2, 2, true},
+		{"ghost combo", 16, 12, 1, 1, 0, 0, false},
+		{"ghosts", 17, 13, 16, 16, 0, 0, false},
+		{"pacman", 33, 29, 2, 2, 0, 0, false},
+		{"fruit", 35, 31, 5, 4, 0, 0, false},
+		{"pellets", 40, 35, 4 * rows, 4 * rows, 0, 0, false},
+		{"seed", 40 + 4*rows, 35 + 4*rows, 8, 8,
+			0xfffffffffffffffe, 0xfffffffffffffffe, true},
+		{"wave", 48 + 4*rows, 43 + 4*rows, 1, 1, 4, 4, true},
+		{"super pellets", 49 + 4*rows, 44 + 4*rows, 4 * rows, 4 * rows,
+			0, 0, false},
+		{"release", 49 + 8*rows, 44 + 8*rows, 7, 7, 0, 0, false},
+		{"elroy", 56 + 8*rows, 51 + 8*rows, 1, 1, 1, 1, true},
+		{"speeds", 57 + 8*rows, 52 + 8*rows, 6, 6, 0, 0, false},
+	}
+
+	for _, legacy := range []bool{false, true} {
+		ConfigLegacyProtocol(legacy)
+		outputBuf := make([]byte, serBufSize)
+		serLen := gs.serFull(outputBuf, 0)
+
+		// Each field should start where the last one ended
+		nextIdx := 0
+		for _, f := range fields {
+			idx, size, value := f.idx, f.size, f.value
+			if legacy {
+				idx, size, value = f.legacyIdx, f.legacySize, f.legacyVal
+			}
+			if idx != nextIdx {
+				t.Fatalf("legacy = %t: %s expected at byte %d, but the "+
+					"previous field ends at byte %d", legacy, f.name, idx,
+					nextIdx)
+			}
+			nextIdx = idx + size
+
+			// The mode should be where clients are told to look for it
+			if f.name == "mode" && serGameModeIdx() != idx {
+				t.Fatalf("legacy = %t: mode index is %d, expected %d", legacy,
+					serGameModeIdx(), idx)
+			}
+
+			// Check the value, if known
+			if got := readUint(outputBuf, idx, size); f.checked && got != value {
+				t.Errorf("legacy = %t: %s (bytes %d-%d) is %d, expected %d",
+					legacy, f.name, idx, nextIdx-1, got, value)
+			}
+		}
+
+		// The fields should cover the whole frame
+		if serLen != nextIdx {
+			t.Fatalf("legacy = %t: serialized %d bytes, expected %d",
+				legacy, serLen, nextIdx)
+		}
+	}
+}
+
+/*
+Check that the super pellet bitmap in a frame marks exactly the maze's super
+pellets, and still marks them once they are collected
+*/
+func TestSerSuperPellets(t *testing.T) {
+
+	// Use the current protocol, where the super pellets follow the wave
+	prevLegacyProtocol := legacyProtocol
+	t.Cleanup(func() { ConfigLegacyProtocol(prevLegacyProtocol) })
+	ConfigLegacyProtocol(false)
+	superIdx := 49 + 4*int(mazeRows)
+
+	// Check the bitmap against the maze file
+	gs := newGameState(1)
+	lines := strings.Split(string(defaultMazeFile), "\n")
+	checkBitmap := func() {
+		t.Helper()
+		outputBuf := make([]byte, serBufSize)
+		gs.serFull(outputBuf, 0)
+		for row := int8(0); row < mazeRows; row++ {
+			bits := uint32(readUint(outputBuf, superIdx+4*int(row), 4))
+			for col := int8(0); col < mazeCols; col++ {
+				super := lines[row][col] == mazeSuperPellet
+				if getBit(bits, col) != super {
+					t.Fatalf("(%d, %d): super pellet bit %t, expected %t",
+						row, col, getBit(bits, col), super)
+				}
+			}
+		}
+	}
+	checkBitmap()
+
+	// Collecting a super pellet should clear its pellet, but not its bit
+	gs.collectPellet(3, 1)
+	if lines[3][1] != mazeSuperPellet || gs.pelletAt(3, 1) {
+		t.Fatal("(3, 1) should be a collected super pellet")
+	}
+	checkBitmap()
+}
@@ -0,0 +1,276 @@
+package game
+
+import (
+	"strconv"
+)
+
+/*
+Exported aliases of the game's enum-like declarations, so that code outside
+of this package can interpret the simulator's accessors
+*/
+const (
+	// Directions
+	DirUp    = up
+	DirLeft  = left
+	DirDown  = down
+	DirRight = right
+	DirNone  = none
+
+	// Game modes
+	ModePaused  = paused
+	ModeScatter = scatter
+	ModeChase   = chase
+
+	// Ghost colors
+	GhostRed    = red
+	GhostPink   = pink
+	GhostCyan   = cyan
+	GhostOrange = orange
+	NumGhosts   = numColors
+)
+
+/*
+A copy of the position and direction of an agent (rows and columns of
+32 or more mean that the agent is not on the maze)
+*/
+type Location struct {
+	Row int8
+	Col int8
+	Dir uint8
+}
+
+// Copy a location state into an exported location
+func newLocation(loc *locationState) Location {
+	row, col := loc.getCoords()
+	return Location{
+		Row: row,
+		Col: col,
+		Dir: loc.getDir(),
+	}
+}
+
+// Determine whether a location is on the maze
+func (loc Location) IsEmpty() bool {
+	return loc.Row >= 32 || loc.Col >= 32
+}
+
+// A copy of the attributes of a ghost
+type Ghost struct {
+	Color        uint8
+	Loc          Location // Current location
+	PlannedDir   uint8    // Direction the ghost plans to move in next
+	FrightSteps  uint8    // Steps left in the frightened state
+	TrappedSteps uint8    // Steps left in the trapped state
+	Held         bool     // Whether the ghost waits for the pellet counters
+	Pellets      uint8    // Pellets counted towards leaving the ghost house
+	Spawning     bool     // Whether the ghost is leaving the ghost house
+	Eaten        bool     // Whether the ghost was just eaten (or is eyes)
+	Eyes         bool     // Whether the ghost is returning home as eyes
+}
+
+/*
+A simulator object, to drive the game rules in-process (without a web broker
+or a wall clock) - each step mirrors one iteration of the game engine loop,
+so a simulator given the same seed and commands plays out the same game.
+
+Note: the game state logs events to the standard logger, so callers running
+many games may want to silence it with log.SetOutput(io.Discard)
+*/
+type Simulator struct {
+	state      *gameState
+	justTicked bool          // whether the last step was a tick
+	history    *stateHistory // snapshots at each update (nil for clones)
+}
+
+// Create a new simulator, with a game using the given random seed
+func NewSimulator(seed int64) *Simulator {
+
+	// Start the game the same way the game engine does
+	sim := Simulator{
+		state:      newGameState(seed),
+		justTicked: true,
+		history:    newStateHistory(historyCapacity),
+	}
+	sim.update()
+
+	// Return the simulator
+	return &sim
+}
+
+/*
+Create an independent copy of the simulator, which plays out exactly as the
+original would if given the same commands (useful for lookahead search) -
+to stay cheap, the copy keeps no snapshots, so it can't be rewound
+*/
+func (sim *Simulator) Clone() *Simulator {
+	return &Simulator{
+		state:      sim.state.clone(),
+		justTicked: sim.justTicked,
+	}
+}
+
+// Reset the game, as if a reset command with the given seed was received
+func (sim *Simulator) Reset(seed int64) {
+	sim.Step([]byte("r" + strconv.FormatInt(seed, 10)))
+}
+
+/*
+Advance the game by one tick, after applying the given commands (using the
+same byte messages that clients send to the server)
+*/
+func (sim *Simulator) Step(commands ...[]byte) {
+
+	// Forget the game events from the last step
+	sim.state.events = nil
+
+	// Apply each command in order, rewinding or resetting if necessary
+	for _, msg := range commands {
+		if len(msg) == 0 {
+			continue
+		}
+		if msg[0] == 'u' {
+			if restored := sim.history.interpretRewind(sim.state, msg); restored != nil {
+				sim.state = restored
+			}
+		} else if sim.state.interpretCommand(msg) {
+			sim.state = newResetGameState(getResetSeed(msg))
+			sim.history.clear()
+			sim.justTicked = true
+		}
+	}
+
+	// Increment the number of ticks
+	if !sim.state.isPaused() {
+		sim.justTicked = true
+		sim.state.nextTick()
+	} else {
+		sim.justTicked = false
+	}
+
+	// Update the game state, if the update period has elapsed
+	sim.update()
+}
+
+// Update the game state if the simulator just ticked onto an update
+func (sim *Simulator) update() {
+	if sim.justTicked && sim.state.updateReady() {
+		sim.state.update()
+		sim.history.push(sim.state)
+	} else if sim.justTicked {
+		sim.state.updateBetweenSteps()
+	}
+}
+
+// Get the game events that happened during the last step
+func (sim *Simulator) Events() []GameEvent {
+	return sim.state.events
+}
+
+// Serialize the game state, in the same format the server sends to clients
+func (sim *Simulator) Snapshot() []byte {
+	outputBuf := make([]byte, serBufSize)
+	serLen := sim.state.serFull(outputBuf, 0)
+	return outputBuf[:serLen]
+}
+
+/******************************** Game Accessors ******************************/
+
+// Get the random seed of the current game
+func (sim *Simulator) Seed() int64 {
+	return sim.state.getSeed()
+}
+
+// Get the current number of ticks
+func (sim *Simulator) Ticks() uint32 {
+	return sim.state.getCurrTicks()
+}
+
+// Get the number of ticks per update
+func (sim *Simulator) UpdatePeriod() uint8 {
+	return sim.state.getUpdatePeriod()
+}
+
+// Get the current game mode (paused, scatter, or chase)
+func (sim *Simulator) Mode() uint8 {
+	return sim.state.getMode()
+}
+
+// Get the last unpaused game mode (the current mode, unless paused)
+func (sim *Simulator) LastUnpausedMode() uint8 {
+	return sim.state.getLastUnpausedMode()
+}
+
+// Get the number of steps until the mode changes
+func (sim *Simulator) ModeSteps() uint8 {
+	return sim.state.getModeSteps()
+}
+
+// Get the current score
+func (sim *Simulator) Score() uint32 {
+	return sim.state.getScore()
+}
+
+// Get the current level
+func (sim *Simulator) Level() uint8 {
+	return sim.state.getLevel()
+}
+
+// Get the number of lives left
+func (sim *Simulator) Lives() uint8 {
+	return sim.state.getLives()
+}
+
+/****************************** Agent Accessors *******************************/
+
+// Get the location of Pacman
+func (sim *Simulator) Pacman() Location {
+	return newLocation(sim.state.pacmanLoc)
+}
+
+// Get the location of the fruit, and whether it exists
+func (sim *Simulator) Fruit() (Location, bool) {
+	return newLocation(sim.state.fruitLoc), sim.state.fruitExists()
+}
+
+// Get the attributes of a ghost, given its color
+func (sim *Simulator) Ghost(color uint8) Ghost {
+	return sim.state.view().Ghost(color)
+}
+
+// Get the attributes of all the ghosts, in order of color
+func (sim *Simulator) Ghosts() []Ghost {
+	ghosts := make([]Ghost, numColors)
+	for color := uint8(0); color < numColors; color++ {
+		ghosts[color] = sim.Ghost(color)
+	}
+	return ghosts
+}
+
+/****************************** Maze Accessors ********************************/
+
+// Get the number of pellets left
+func (sim *Simulator) NumPellets() uint16 {
+	return sim.state.getNumPellets()
+}
+
+// Determine whether a pellet is at a given location
+func (sim *Simulator) PelletAt(row int8, col int8) bool {
+	return sim.state.pelletAt(row, col)
+}
+
+/*
+Get a copy of the pellets, with each uint32 acting as a bit array
+(column 0 at bit 0) for a row of the maze
+*/
+func (sim *Simulator) Pellets() []uint32 {
+
+	// Copy the pellets
+	pellets := make([]uint32, len(sim.state.pellets))
+	copy(pellets, sim.state.pellets[:])
+	return pellets
+}
+
+// Determine whether a wall is at a given location
+func (sim *Simulator) WallAt(row int8, col int8) bool {
+	return sim.state.wallAt(row, col)
+}
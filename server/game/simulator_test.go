@@ -0,0 +1,51 @@
+package game
+
+import (
+	"bytes"
+	"testing"
+)
+
+// A scripted list of commands (by step) to drive simulated games with
+var testCommands = map[int]string{
+	0:   "P",
+	20:  "a",
+	40:  "a",
+	60:  "w",
+	90:  "d",
+	130: "s",
+	200: "p",
+	210: "P",
+	300: "a",
+	400: "d",
+}
+
+// Play out a simulated game with a seed and the scripted commands
+func playScripted(seed int64, steps int) [][]byte {
+	sim := NewSimulator(seed)
+	frames := [][]byte{sim.Snapshot()}
+	for step := 0; step < steps; step++ {
+		if cmd, ok := testCommands[step]; ok {
+			sim.Step([]byte(cmd))
+		} else {
+			sim.Step()
+		}
+		frames = append(frames, sim.Snapshot())
+	}
+	return frames
+}
+
+/*
+Check that simulators given the same seed and commands play out the same
+game, frame for frame
+*/
+func TestSimulatorDeterminism(t *testing.T) {
+
+	// Play the same game twice
+	first := playScripted(42, 1000)
+	second := playScripted(42, 1000)
+	for idx := range first {
+		if !bytes.Equal(first[idx], second[idx]) {
+			t.Fatalf("frame %d differs between games with the same seed", idx)
+		}
+	}
+}
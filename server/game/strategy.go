@@ -0,0 +1,269 @@
+package game
+
+import (
+	"fmt"
+)
+
+/*
+A ghost strategy object, to decide where a ghost heads for in chase mode -
+strategies are given a read-only view of the game, and should only depend on
+it (not on clocks or global random numbers), so that games stay reproducible
+*/
+type GhostStrategy interface {
+
+	// Get the target location (row, col) of a ghost of the given color
+	ChaseTarget(view GameView, color uint8) (int8, int8)
+}
+
+/*
+A ghost strategy which can also choose a ghost's direction in chase mode,
+rather than leaving it to the default (the valid direction that brings the
+ghost closest to its target)
+*/
+type GhostDirStrategy interface {
+	GhostStrategy
+
+	/*
+		Choose the direction for a ghost of the given color to leave the
+		location (where it will be after its current move) by, given which
+		directions are valid - returning false (or an invalid direction)
+		falls back to the default
+	*/
+	ChaseDir(view GameView, color uint8, from Location,
+		valid [numDirs]bool) (uint8, bool)
+}
+
+/*
+The ghost strategies that can be assigned by name (including any custom
+strategies, once registered)
+*/
+var namedGhostStrategies = map[string]GhostStrategy{
+	"red":    redStrategy{},
+	"pink":   pinkStrategy{},
+	"cyan":   cyanStrategy{},
+	"orange": orangeStrategy{},
+}
+
+// The strategy that new ghosts of each color use
+var ghostStrategies = [numColors]GhostStrategy{
+	redStrategy{},    // red
+	pinkStrategy{},   // pink
+	cyanStrategy{},   // cyan
+	orangeStrategy{}, // orange
+}
+
+/*
+The names of the strategies that new ghosts of each color use (empty for
+strategies assigned directly, rather than by name)
+*/
+var ghostStrategyNames = [numColors]string{"red", "pink", "cyan", "orange"}
+
+/*
+Register a custom ghost strategy under a name, so that it can be assigned to
+ghosts through a configuration (must be done before any game engine or
+simulator runs, since the strategies are read without locking)
+*/
+func RegisterGhostStrategy(name string, strategy GhostStrategy) {
+	namedGhostStrategies[name] = strategy
+}
+
+/*
+Configure the strategy that new ghosts of a color use (must be done before any
+game engine or simulator runs, since the strategies are read without locking)
+*/
+func ConfigGhostStrategy(color uint8, strategy GhostStrategy) {
+	ghostStrategies[color] = strategy
+	ghostStrategyNames[color] = ""
+}
+
+/*
+Configure the strategies of the ghosts by name, given a map from ghost colors
+to strategy names (colors left out keep their strategies) - like
+ConfigGhostStrategy, this must be done before any game engine or simulator
+runs. If any color or name is unknown, no strategies are changed
+*/
+func ConfigGhostStrategies(names map[string]string) error {
+
+	// Look up each of the strategies before assigning any
+	var strategies [numColors]GhostStrategy
+	var strategyNames [numColors]string
+	for colorName, name := range names {
+
+		// Find the color of the ghost
+		color := numColors
+		for c := uint8(0); c < numColors; c++ {
+			if ghostNames[c] == colorName {
+				color = c
+			}
+		}
+		if color == numColors {
+			return fmt.Errorf("no ghost named %q", colorName)
+		}
+
+		// Find the strategy
+		strategy, ok := namedGhostStrategies[name]
+		if !ok {
+			return fmt.Errorf("%s: no ghost strategy named %q", colorName, name)
+		}
+		strategies[color] = strategy
+		strategyNames[color] = name
+	}
+
+	// Assign the strategies that were given, keeping track of their names
+	for color, strategy := range strategies {
+		if strategy != nil {
+			ConfigGhostStrategy(uint8(color), strategy)
+			ghostStrategyNames[color] = strategyNames[color]
+		}
+	}
+	return nil
+}
+
+/****************************** Built-in Strategies ***************************/
+
+// Red's strategy: target Pacman's exact location
+type redStrategy struct{}
+
+func (redStrategy) ChaseTarget(view GameView, color uint8) (int8, int8) {
+	return view.state.pacmanLoc.getCoords()
+}
+
+// Pink's strategy: target 4 spaces ahead of Pacman's location
+type pinkStrategy struct{}
+
+func (pinkStrategy) ChaseTarget(view GameView, color uint8) (int8, int8) {
+	return view.PacmanAhead(4)
+}
+
+/*
+Cyan's strategy: target the red ghost's location, reflected about 2 spaces
+ahead of Pacman
+*/
+type cyanStrategy struct{}
+
+func (cyanStrategy) ChaseTarget(view GameView, color uint8) (int8, int8) {
+
+	// Get the 'pivot' square, 2 steps ahead of Pacman
+	pivotRow, pivotCol := view.PacmanAhead(2)
+
+	// Get the current location of the red ghost
+	redRow, redCol := view.state.ghosts[red].loc.getCoords()
+
+	// Return the pair of coordinates of the calculated target
+	return (2*pivotRow - redRow),
+		(2*pivotCol - redCol)
+}
+
+/*
+Orange's strategy: target Pacman's exact location (the same as red's target
+most of the time), unless close enough to Pacman, in which case the ghost
+targets its scatter location
+*/
+type orangeStrategy struct{}
+
+func (orangeStrategy) ChaseTarget(view GameView, color uint8) (int8, int8) {
+
+	// Get Pacman's current location
+	pacmanRow, pacmanCol := view.state.pacmanLoc.getCoords()
+
+	// Get the ghost's current location
+	ghost := view.state.ghosts[color]
+	ghostRow, ghostCol := ghost.loc.getCoords()
+
+	// If Pacman is far enough from the ghost, return Pacman's location
+	if view.state.distSq(ghostRow, ghostCol, pacmanRow, pacmanCol) >= 64 {
+		return pacmanRow, pacmanCol
+	}
+
+	// Otherwise, return the scatter location of the ghost
+	return ghost.scatterTarget.getCoords()
+}
+
+/********************************** Game View *********************************/
+
+/*
+A read-only view of a game, for ghost strategies to decide with (only valid
+during the call it was passed to)
+*/
+type GameView struct {
+	state *gameState
+}
+
+// Get a read-only view of the game state
+func (gs *gameState) view() GameView {
+	return GameView{state: gs}
+}
+
+// Get the current number of ticks
+func (view GameView) Ticks() uint32 {
+	return view.state.getCurrTicks()
+}
+
+// Get the mode that the ghosts are following (the last unpaused mode)
+func (view GameView) Mode() uint8 {
+	return view.state.getLastUnpausedMode()
+}
+
+// Get the current level
+func (view GameView) Level() uint8 {
+	return view.state.getLevel()
+}
+
+// Get the number of pellets left
+func (view GameView) NumPellets() uint16 {
+	return view.state.getNumPellets()
+}
+
+// Get the location of Pacman
+func (view GameView) Pacman() Location {
+	return newLocation(view.state.pacmanLoc)
+}
+
+/*
+Get the location some spaces ahead of Pacman (for targeting) - with the
+arcade game's overflow bug (see Rules.UpOverflowBug), it is shifted the same
+number of spaces to the left when Pacman faces up
+*/
+func (view GameView) PacmanAhead(spaces int8) (int8, int8) {
+	row, col := view.state.pacmanLoc.getAheadCoords(spaces)
+	if view.state.rules.UpOverflowBug && view.state.pacmanLoc.getDir() == up {
+		col -= spaces
+	}
+	return row, col
+}
+
+// Get the attributes of a ghost, given its color
+func (view GameView) Ghost(color uint8) Ghost {
+
+	// Retrieve this ghost's struct
+	g := view.state.ghosts[color]
+
+	// Copy over its attributes
+	return Ghost{
+		Color:        color,
+		Loc:          newLocation(g.loc),
+		PlannedDir:   g.nextLoc.getDir(),
+		FrightSteps:  g.getFrightSteps(),
+		TrappedSteps: g.getTrappedSteps(),
+		Held:         g.isHeld(),
+		Pellets:      g.getPelletCounter(),
+		Spawning:     g.isSpawning(),
+		Eaten:        g.isEaten(),
+		Eyes:         g.isEyes(),
+	}
+}
+
+// Get the scatter target (row, col) of a ghost, given its color
+func (view GameView) ScatterTarget(color uint8) (int8, int8) {
+	return view.state.ghosts[color].scatterTarget.getCoords()
+}
+
+// Determine whether a pellet is at a given location
+func (view GameView) PelletAt(row int8, col int8) bool {
+	return view.state.pelletAt(row, col)
+}
+
+// Determine whether a wall is at a given location
+func (view GameView) WallAt(row int8, col int8) bool {
+	return view.state.wallAt(row, col)
+}
@@ -0,0 +1,163 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+// Restore the ghosts' strategies after a test
+func restoreGhostStrategies(t *testing.T) {
+	prevStrategies, prevNames := ghostStrategies, ghostStrategyNames
+	t.Cleanup(func() {
+		ghostStrategies, ghostStrategyNames = prevStrategies, prevNames
+	})
+}
+
+/*
+Check that configuring strategies by name rejects unknown colors and
+strategies (without changing any strategies), and that colors left out keep
+their strategies
+*/
+func TestConfigGhostStrategies(t *testing.T) {
+	restoreGhostStrategies(t)
+	defaultStrategies, defaultNames := ghostStrategies, ghostStrategyNames
+
+	// Unknown colors or strategy names should change nothing
+	errTests := []struct {
+		names map[string]string
+		err   string
+	}{
+		{map[string]string{"purple": "red"}, `no ghost named "purple"`},
+		{map[string]string{"pink": "red", "cyan": "sideways"},
+			`cyan: no ghost strategy named "sideways"`},
+	}
+	for _, test := range errTests {
+		err := ConfigGhostStrategies(test.names)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("%v: expected an error containing %q, got %v",
+				test.names, test.err, err)
+		}
+		if ghostStrategies != defaultStrategies ||
+			ghostStrategyNames != defaultNames {
+			t.Fatalf("%v: strategies changed despite the error", test.names)
+		}
+	}
+
+	// Only the colors given should change
+	if err := ConfigGhostStrategies(map[string]string{"pink": "red"}); err != nil {
+		t.Fatal(err)
+	}
+	expectedNames := [numColors]string{"red", "red", "cyan", "orange"}
+	if ghostStrategyNames != expectedNames {
+		t.Fatalf("strategy names %v, expected %v", ghostStrategyNames,
+			expectedNames)
+	}
+	for color, strategy := range ghostStrategies {
+		expected := defaultStrategies[color]
+		if color == int(pink) {
+			expected = redStrategy{}
+		}
+		if strategy != expected {
+			t.Fatalf("%s ghost strategy %T, expected %T", ghostNames[color],
+				strategy, expected)
+		}
+	}
+}
+
+// A strategy that chases like red, but always picks a given direction
+type fixedDirStrategy struct {
+	redStrategy
+	dir uint8
+	ok  bool
+}
+
+func (s fixedDirStrategy) ChaseDir(view GameView, color uint8, from Location,
+	valid [numDirs]bool) (uint8, bool) {
+	return s.dir, s.ok
+}
+
+/*
+Check that a direction strategy picks red's direction in chase mode (unless
+the direction is invalid, or the strategy declines), but not in scatter mode
+*/
+func TestGhostDirStrategy(t *testing.T) {
+	restoreGhostStrategies(t)
+
+	// From this junction, chasing Pacman heads down, and scattering heads up
+	tests := []struct {
+		name     string
+		strategy fixedDirStrategy
+		mode     uint8
+		expected uint8
+	}{
+		{"chosen", fixedDirStrategy{dir: up, ok: true}, chase, up},
+		{"declined", fixedDirStrategy{dir: up, ok: false}, chase, down},
+		{"reversing", fixedDirStrategy{dir: right, ok: true}, chase, down},
+		{"out of range", fixedDirStrategy{dir: numDirs, ok: true}, chase, down},
+		{"scatter", fixedDirStrategy{dir: down, ok: true}, scatter, up},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ConfigGhostStrategy(red, test.strategy)
+			gs := newGameState(1)
+			gs.setMode(test.mode)
+			gs.pacmanLoc.updateCoords(23, 13)
+			ghost := gs.ghosts[red]
+			ghost.setTrappedSteps(0)
+			ghost.setSpawning(false)
+			ghost.loc.updateCoords(5, 7)
+			ghost.loc.updateDir(left)
+			ghost.plan()
+			if dir := ghost.nextLoc.getDir(); dir != test.expected {
+				t.Fatalf("red heads %s, expected %s", dirNames[dir],
+					dirNames[test.expected])
+			}
+		})
+	}
+}
+
+/*
+Check that with the up overflow bug, the spaces ahead of Pacman (that pink and
+cyan target) shift left by as many spaces when Pacman faces up, and only then
+*/
+func TestUpOverflowBug(t *testing.T) {
+	tests := []struct {
+		name   string
+		bug    bool
+		dir    uint8
+		spaces int8
+		row    int8
+		col    int8
+	}{
+		{"4 spaces facing up", true, up, 4, 19, 9},
+		{"2 spaces facing up", true, up, 2, 21, 11},
+		{"facing left", true, left, 4, 23, 9},
+		{"facing down", true, down, 2, 25, 13},
+		{"without the bug", false, up, 4, 19, 13},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configTestRules(t, func(rules *Rules) {
+				rules.UpOverflowBug = test.bug
+			})
+			gs := newGameState(1)
+			gs.pacmanLoc.updateCoords(23, 13)
+			gs.pacmanLoc.updateDir(test.dir)
+			row, col := gs.view().PacmanAhead(test.spaces)
+			if row != test.row || col != test.col {
+				t.Fatalf("%d spaces ahead is (%d, %d), expected (%d, %d)",
+					test.spaces, row, col, test.row, test.col)
+			}
+		})
+	}
+
+	// Pink's target should be shifted the same way
+	configTestRules(t, func(rules *Rules) { rules.UpOverflowBug = true })
+	gs := newGameState(1)
+	gs.pacmanLoc.updateCoords(23, 13)
+	gs.pacmanLoc.updateDir(up)
+	row, col := pinkStrategy{}.ChaseTarget(gs.view(), pink)
+	if row != 19 || col != 9 {
+		t.Fatalf("pink targets (%d, %d), expected (19, 9)", row, col)
+	}
+}
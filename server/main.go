@@ -2,16 +2,139 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"pacbot_server/game"
 	"pacbot_server/webserver"
 	"sync"
 	"time"
 )
 
+// An engine (game or replay) that produces frames for the web broker
+type engine interface {
+	RunLoop()
+	Quit()
+}
+
+/*
+Load the rules profile named in the configuration (from rules.json in the
+base directory), unless the rules are already known - as in a recording
+*/
+func loadRules(conf *Configuration) {
+
+	// Rules that are already filled in are used as-is
+	if conf.Rules != nil {
+		return
+	}
+
+	// An empty profile name means the built-in rules
+	if conf.RulesProfile == "" {
+		return
+	}
+
+	// Load the rules profile
+	rules, err := game.LoadRules("../rules.json", conf.RulesProfile)
+	if err != nil {
+		log.Fatalf("\033[35m\033[1mERR:  Could not load rules (%s)\033[0m\n", err)
+	}
+	conf.Rules = rules
+	log.Printf("\033[35mLOG:  Loaded rules profile %q\033[0m\n", rules.Name)
+}
+
+/*
+Use the configuration info to set up the rules of the game (and if replaying,
+the maze and ghost strategies of the recording)
+*/
+func configureGame(conf Configuration, rec *game.Recording) {
+	game.ConfigNumActiveGhosts(min(conf.NumActiveGhosts, 4))
+	game.ConfigRandomSeed(conf.RandomSeed)
+	game.ConfigLegacyProtocol(conf.LegacyProtocol)
+
+	// Assign the ghosts' strategies, if any are configured
+	if err := game.ConfigGhostStrategies(conf.GhostStrategies); err != nil {
+		log.Fatalf("\033[35m\033[1mERR:  Invalid ghost strategies (%s)\033[0m\n", err)
+	}
+
+	// Load the maze file, if one is configured (otherwise, use the default)
+	if err := game.ConfigMazeFile(conf.MazeFile); err != nil {
+		log.Fatalf("\033[35m\033[1mERR:  Could not load maze (%s)\033[0m\n", err)
+	}
+	if conf.MazeFile != "" {
+		log.Printf("\033[35mLOG:  Loaded maze from %s\033[0m\n", conf.MazeFile)
+	}
+
+	// Replay a match on the maze and ghost strategies it was recorded with
+	if rec != nil {
+		if err := rec.Configure(); err != nil {
+			log.Fatalf("\033[35m\033[1mERR:  Could not configure replay (%s)\033[0m\n", err)
+		}
+	}
+
+	// Use the rules from the rules profile, if one is loaded
+	if conf.Rules != nil {
+		if err := game.ConfigRules(conf.Rules); err != nil {
+			log.Fatalf("\033[35m\033[1mERR:  Invalid rules (%s)\033[0m\n", err)
+		}
+	}
+
+	/*
+		Legacy clients expect every message to be a frame, so they get no text
+		messages (server info or game events)
+	*/
+	if conf.LegacyProtocol {
+		return
+	}
+
+	// Report the rules in force (and the maze) to clients when they connect
+	info, err := json.Marshal(struct {
+		Rules *game.Rules
+		Maze  *game.MazeInfo
+	}{game.CurrentRules(), game.CurrentMaze()})
+	if err != nil {
+		log.Fatalf("\033[35m\033[1mERR:  Could not encode server info (%s)\033[0m\n", err)
+	}
+	webserver.ConfigServerInfo(info)
+
+	// Pass game events on to clients as they happen
+	game.ConfigEventHandler(webserver.BroadcastText)
+}
+
+// Create a game engine, depending on the configured clock and recording
+func newGameEngine(conf Configuration, webBroadcastCh chan<- []byte,
+	webResponseCh <-chan []byte, wgQuit *sync.WaitGroup) *game.GameEngine {
+
+	var ge *game.GameEngine
+	switch conf.HeadlessMode {
+	case "fast": // Ticks advance as fast as possible
+		ge = game.NewHeadlessGameEngine(webBroadcastCh, webResponseCh, wgQuit, false)
+		log.Println("\033[35mLOG:  Game engine running headless (fast-forward)\033[0m")
+	case "step": // Ticks advance only when clients request them
+		ge = game.NewHeadlessGameEngine(webBroadcastCh, webResponseCh, wgQuit, true)
+		log.Println("\033[35mLOG:  Game engine running headless (stepped)\033[0m")
+	case "": // Ticks advance with the wall clock
+		ge = game.NewGameEngine(webBroadcastCh, webResponseCh, wgQuit, conf.GameFPS)
+	default:
+		log.Fatalf("\033[35m\033[1mERR:  Unknown headless mode %q (expected "+
+			"\"fast\", \"step\" or \"\")\033[0m\n", conf.HeadlessMode)
+	}
+
+	// Record the match to a file, if a directory for recordings is configured
+	if conf.RecordingDir != "" {
+		err := ge.StartRecording(conf.RecordingDir, conf, conf.RecordFrames)
+		if err != nil {
+			log.Printf("\033[35m\033[1mERR:  Could not start recording (%s)\033[0m\n",
+				err)
+		}
+	}
+
+	// Return the game engine
+	return ge
+}
+
 func main() {
 
 	// Disable logging timestamps
@@ -20,6 +143,39 @@ func main() {
 	// Get the configuration info (config.go)
 	conf := GetConfig()
 
+	// If asked to replay a match (pacbot_server replay <file>), read it first
+	var rec *game.Recording
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		if len(os.Args) != 3 {
+			log.Fatalln("Usage: pacbot_server replay <file>")
+		}
+		var err error
+		rec, err = game.ReadRecording(os.Args[2])
+		if err != nil {
+			log.Fatalf("\033[35m\033[1mERR:  Could not read recording %s (%s)\033[0m\n",
+				os.Args[2], err)
+		}
+	}
+
+	/*
+		Configure the game (the same way as the recorded match, if replaying)
+		before any clients can connect, so they all get the same server info
+	*/
+	gameConf := conf
+	if rec != nil {
+		if err := json.Unmarshal(rec.Config(), &gameConf); err != nil {
+			log.Printf("\033[35mWARN: Could not read recorded config (%s)\033[0m\n",
+				err)
+		}
+
+		// The recorded maze (if any) is used in place of the maze file
+		if rec.Maze() != nil {
+			gameConf.MazeFile = ""
+		}
+	}
+	loadRules(&gameConf)
+	configureGame(gameConf, rec)
+
 	// Use this configuration info to set up server subunits
 	webserver.ConfigOneClientPerIP(conf.OneClientPerIP)
 	webserver.ConfigTrustedClientIPs(conf.TrustedClientIPs)
@@ -52,8 +208,16 @@ func main() {
 	}()
 
 	// Game engine setup (package game)
-	game.ConfigNumActiveGhosts(min(conf.NumActiveGhosts, 4))
-	ge := game.NewGameEngine(webBroadcastCh, webResponseCh, &wgQuit, conf.GameFPS)
+	var ge engine
+	if rec != nil {
+
+		// Serve the re-simulated match in place of a live game
+		ge = game.NewReplayEngine(rec, webBroadcastCh, webResponseCh, &wgQuit,
+			gameConf.GameFPS)
+		log.Printf("\033[35mLOG:  Replaying match from %s\033[0m\n", os.Args[2])
+	} else {
+		ge = newGameEngine(gameConf, webBroadcastCh, webResponseCh, &wgQuit)
+	}
 	go ge.RunLoop() // Run the game engine loop asynchronously
 
 	// Set the enable for game command logging to be false by default
@@ -108,3 +108,22 @@ func (wb *WebBroker) RunLoop() {
 		}
 	}
 }
+
+/*
+Send a text message (such as a game event) to all web sessions - slow
+clients miss the message rather than holding up the caller
+*/
+func BroadcastText(msg []byte) {
+	muOWS.RLock()
+	{
+		for ws := range openWebSessions {
+			select {
+			case ws.textCh <- msg:
+			default:
+				log.Printf("\033[35mWARN: A web-session text channel was full"+
+					" (client = %s)\033[0m\n", getIP(ws.conn))
+			}
+		}
+	}
+	muOWS.RUnlock()
+}
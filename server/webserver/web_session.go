@@ -27,6 +27,17 @@ func ConfigTrustedClientIPs(_trustedClientIPs []string) {
 	}
 }
 
+/*
+Info about the server (as JSON) to send to each client as a text message
+when it connects, before any frames
+*/
+var serverInfo []byte
+
+// Set the server info to send to new clients based on a configuration
+func ConfigServerInfo(_serverInfo []byte) {
+	serverInfo = _serverInfo
+}
+
 // Store the responses from trusted clients in a (send-only) channel
 var responseCh chan<- []byte
 
@@ -50,7 +61,8 @@ func getIP(conn *websocket.Conn) string {
 // Web session object, for keeping track of individual websocket sessions
 type webSession struct {
 	sendCh chan []byte
-	readEn bool // read enabled (allowed by IP whitelist)
+	textCh chan []byte // text messages (such as game events)
+	readEn bool        // read enabled (allowed by IP whitelist)
 	conn   *websocket.Conn
 	sync.Mutex
 }
@@ -59,6 +71,7 @@ type webSession struct {
 func newWebSession(conn *websocket.Conn) *webSession {
 	return &webSession{
 		sendCh: make(chan []byte, 10),
+		textCh: make(chan []byte, 10),
 		readEn: true,
 		conn:   conn,
 	}
@@ -223,13 +236,27 @@ func (ws *webSession) readLoop() {
 	}
 }
 
-// Sending websocket data (binary)
+// Sending websocket data (binary, after the server info as text)
 func (ws *webSession) sendLoop() {
+
+	// Start by sending the server info, if there is any
+	if len(serverInfo) > 0 {
+		if err := ws.conn.WriteMessage(websocket.TextMessage, serverInfo); err != nil {
+			return
+		}
+	}
+
 	// "While" loop, keep sending until the connection closes
 	for {
 
-		// Block until the next message is ready
-		msg := <-ws.sendCh
+		// Block until the next message (frame or text) is ready
+		var msg []byte
+		msgType := websocket.BinaryMessage
+		select {
+		case msg = <-ws.sendCh:
+		case msg = <-ws.textCh:
+			msgType = websocket.TextMessage
+		}
 
 		// nil means we are told to exit
 		if msg == nil {
@@ -237,7 +264,7 @@ func (ws *webSession) sendLoop() {
 		}
 
 		// Try writing the message
-		if err := ws.conn.WriteMessage(websocket.BinaryMessage, msg); err != nil {
+		if err := ws.conn.WriteMessage(msgType, msg); err != nil {
 
 			// Types of errors which we intentionally catch and return from
 			clientCloseErr := websocket.IsCloseError(